@@ -2,10 +2,12 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/Sceptre-Cybersec/gohammer/utils"
+	"gopkg.in/yaml.v3"
 )
 
 func splitMultiInt(value string) ([]int, error) {
@@ -35,6 +37,27 @@ func splitMultiString(value string) ([]string, error) {
 	return multiArr, nil
 }
 
+// unmarshalMultiFlag lets config file values for multi flags be written either as a single
+// flag style value or as a list where each element is treated as a separate flag
+func unmarshalMultiFlag(value *yaml.Node, set func(string) error) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return set(value.Value)
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: expected a single value in list", item.Line)
+			}
+			err := set(item.Value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a value or a list of values", value.Line)
+}
+
 type multiStringFlag []string
 
 func (m *multiStringFlag) String() string {
@@ -44,6 +67,10 @@ func (m *multiStringFlag) Set(value string) error {
 	*m = append(*m, value)
 	return nil
 }
+func (m *multiStringFlag) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
 
 type multiSplitStringFlag []string
 
@@ -55,6 +82,10 @@ func (m *multiSplitStringFlag) Set(value string) error {
 	*m = append(*m, splitStrings...)
 	return err
 }
+func (m *multiSplitStringFlag) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
 
 type multiSplitIntFlagOrAll []int
 
@@ -72,6 +103,11 @@ func (m *multiSplitIntFlagOrAll) String() string {
 	return ""
 }
 
+func (m *multiSplitIntFlagOrAll) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
+
 type multiSplitIntFlag []int
 
 func (m *multiSplitIntFlag) String() string {
@@ -82,85 +118,290 @@ func (m *multiSplitIntFlag) Set(value string) error {
 	*m = append(*m, splitInts...)
 	return err
 }
+func (m *multiSplitIntFlag) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
+
+// IntRange is an inclusive range of integers, open bounds use the smallest or largest int
+type IntRange struct {
+	Low  int
+	High int
+}
+
+// Contains returns true if the integer is in the range
+func (r IntRange) Contains(i int) bool {
+	return i >= r.Low && i <= r.High
+}
+
+func (r IntRange) String() string {
+	switch {
+	case r.Low == r.High:
+		return strconv.Itoa(r.Low)
+	case r.Low == math.MinInt:
+		return "<=" + strconv.Itoa(r.High)
+	case r.High == math.MaxInt:
+		return ">=" + strconv.Itoa(r.Low)
+	}
+	return fmt.Sprintf("%d-%d", r.Low, r.High)
+}
+
+func (r IntRange) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
+// ParseIntRange parses a single number (500), a range (100-200) or an open bound (>500, >=500, <10, <=10)
+func ParseIntRange(value string) (IntRange, error) {
+	value = strings.TrimSpace(value)
+	prefixes := []string{">=", "<=", ">", "<"}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			i, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(value, prefix)))
+			if err != nil {
+				return IntRange{}, err
+			}
+			switch prefix {
+			case ">=":
+				return IntRange{Low: i, High: math.MaxInt}, nil
+			case "<=":
+				return IntRange{Low: math.MinInt, High: i}, nil
+			case ">":
+				return IntRange{Low: i + 1, High: math.MaxInt}, nil
+			}
+			return IntRange{Low: math.MinInt, High: i - 1}, nil
+		}
+	}
+	bounds := strings.SplitN(value, "-", 2)
+	low, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return IntRange{}, err
+	}
+	high := low
+	if len(bounds) > 1 {
+		high, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return IntRange{}, err
+		}
+	}
+	return IntRange{Low: min(low, high), High: max(low, high)}, nil
+}
+
+func splitMultiRange(value string) ([]IntRange, error) {
+	ranges := []IntRange{}
+	for _, s := range strings.Split(value, ",") {
+		r, err := ParseIntRange(s)
+		if err != nil {
+			fmt.Printf("Error converting range: %s in command line arguments\n", s)
+			return []IntRange{}, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// IntRanges is a list of ranges, a value matches if it is in any of the ranges
+type IntRanges []IntRange
+
+// Contains returns true if the value is in any of the ranges
+func (m IntRanges) Contains(i int) bool {
+	for _, r := range m {
+		if r.Contains(i) {
+			return true
+		}
+	}
+	return false
+}
+
+type multiSplitRangeFlag IntRanges
+
+func (m *multiSplitRangeFlag) String() string {
+	return ""
+}
+func (m *multiSplitRangeFlag) Set(value string) error {
+	ranges, err := splitMultiRange(value)
+	*m = append(*m, ranges...)
+	return err
+}
+func (m *multiSplitRangeFlag) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
+
+// timeRangeFlag is a multiSplitRangeFlag where a single number means greater than or equal to that number
+type timeRangeFlag IntRanges
+
+func (m *timeRangeFlag) String() string {
+	return ""
+}
+func (m *timeRangeFlag) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		r, err := ParseIntRange(s)
+		if err != nil {
+			fmt.Printf("Error converting range: %s in command line arguments\n", s)
+			return err
+		}
+		// a single number keeps the original meaning of the time flags
+		if _, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			r.High = math.MaxInt
+		}
+		*m = append(*m, r)
+	}
+	return nil
+}
+func (m *timeRangeFlag) UnmarshalYAML(value *yaml.Node) error {
+	*m = nil
+	return unmarshalMultiFlag(value, m.Set)
+}
 
 type RequestOptions struct {
-	Url           string
-	Proxy         string
-	Rate          float64
-	Method        string
-	ReqFile       multiStringFlag
-	Headers       multiStringFlag
-	RemoveHeaders multiStringFlag
-	Timeout       int
-	Data          string
-	Http          bool
-	Esc           bool
-	NoUpdateCL    bool
+	Url           string            `yaml:"url" flag:"u"`
+	Proxy         string            `yaml:"proxy" flag:"proxy"`
+	ProxyRotate   string            `yaml:"proxy-rotate" flag:"proxy-rotate"`
+	Rate          float64           `yaml:"rate" flag:"rate"`
+	Method        string            `yaml:"method" flag:"method"`
+	ReqFile       multiStringFlag   `yaml:"request-files" flag:"f"`
+	Headers       multiStringFlag   `yaml:"headers" flag:"H"`
+	RemoveHeaders multiStringFlag   `yaml:"remove-headers" flag:"rH"`
+	Timeout       int               `yaml:"timeout" flag:"to"`
+	Data          string            `yaml:"data" flag:"d"`
+	Http          bool              `yaml:"http" flag:"http"`
+	Raw           bool              `yaml:"raw" flag:"raw"`
+	Http2         bool              `yaml:"http2" flag:"http2"`
+	Esc           bool              `yaml:"esc" flag:"esc"`
+	NoUpdateCL    bool              `yaml:"no-update-cl" flag:"no-update-cl"`
+	CookieJar     string            `yaml:"cookie-jar" flag:"cookie-jar"`
+	CookieFile    string            `yaml:"cookie-file" flag:"cookie-file"`
+	Har           multiStringFlag   `yaml:"har" flag:"har"`
+	Curl          multiStringFlag   `yaml:"curl" flag:"curl"`
+	OpenApi       multiStringFlag   `yaml:"openapi" flag:"openapi"`
+	ImportMatch   string            `yaml:"import-match" flag:"import-match"`
+	Adaptive      bool              `yaml:"adaptive" flag:"adaptive"`
+	ThrottleCodes multiSplitIntFlag `yaml:"throttle-codes" flag:"throttle-codes"`
+	ThrottleSlow  float64           `yaml:"throttle-latency" flag:"throttle-latency"`
+
+	Cookies []utils.FileCookie `yaml:"-"`
+	Limiter *utils.RateLimiter `yaml:"-"`
 }
 
 type GeneralOptions struct {
-	Threads int
-	Retry   int
-	Dos     bool
+	Threads        int               `yaml:"threads" flag:"t"`
+	Retry          int               `yaml:"retry" flag:"retry"`
+	Dos            bool              `yaml:"dos" flag:"dos"`
+	Race           int               `yaml:"race" flag:"race"`
+	AutoCalibrate  bool              `yaml:"auto-calibrate" flag:"ac"`
+	CheckpointFile string            `yaml:"checkpoint" flag:"checkpoint"`
+	Resume         string            `yaml:"-" flag:"resume"`
+	Checkpoint     *utils.Checkpoint `yaml:"-"`
 }
 
 type RecursionOptions struct {
-	Depth            int
-	RecursePosition  int
-	RecurseDelimiter string
-	RecurseCode      multiSplitIntFlag
+	Depth            int               `yaml:"depth" flag:"rd"`
+	RecursePosition  int               `yaml:"position" flag:"rp"`
+	RecurseDelimiter string            `yaml:"delimiter" flag:"rdl"`
+	RecurseCode      multiSplitIntFlag `yaml:"codes" flag:"rc"`
 }
 
 type WordlistOptions struct {
-	Combo      bool
-	Extensions multiSplitStringFlag
-	Files      []string
+	Combo      bool                 `yaml:"combo" flag:"combo"`
+	Extensions multiSplitStringFlag `yaml:"extensions" flag:"e"`
+	Files      []string             `yaml:"files"`
+
+	Rules         string       `yaml:"rules" flag:"rules"`
+	RulesPosition int          `yaml:"rules-position" flag:"rules-pos"`
+	RuleSet       *utils.Rules `yaml:"-"`
 }
 
+// FilterOptions uses the same short names as the command line flags for its yaml keys
 type FilterOptions struct {
-	Mc multiSplitIntFlagOrAll
-	Ms multiSplitIntFlag
-	Mw multiSplitIntFlag
-	Ml multiSplitIntFlag
-	Mt int
-	Mr string
-	Fc multiSplitIntFlag
-	Fs multiSplitIntFlag
-	Fw multiSplitIntFlag
-	Fl multiSplitIntFlag
-	Ft int
-	Fr string
+	Mc multiSplitIntFlagOrAll `yaml:"mc" flag:"mc"`
+	Ms multiSplitRangeFlag    `yaml:"ms" flag:"ms"`
+	Mw multiSplitRangeFlag    `yaml:"mw" flag:"mw"`
+	Ml multiSplitRangeFlag    `yaml:"ml" flag:"ml"`
+	Mt timeRangeFlag          `yaml:"mt" flag:"mt"`
+	Mr string                 `yaml:"mr" flag:"mr"`
+	Fc multiSplitIntFlag      `yaml:"fc" flag:"fc"`
+	Fs multiSplitRangeFlag    `yaml:"fs" flag:"fs"`
+	Fw multiSplitRangeFlag    `yaml:"fw" flag:"fw"`
+	Fl multiSplitRangeFlag    `yaml:"fl" flag:"fl"`
+	Ft timeRangeFlag          `yaml:"ft" flag:"ft"`
+	Fr string                 `yaml:"fr" flag:"fr"`
+
+	Mh  multiStringFlag     `yaml:"mh" flag:"mh"`
+	Mhc multiSplitRangeFlag `yaml:"mhc" flag:"mhc"`
+	Fh  multiStringFlag     `yaml:"fh" flag:"fh"`
+	Fhc multiSplitRangeFlag `yaml:"fhc" flag:"fhc"`
+
+	Expr       string     `yaml:"filter" flag:"filter"`
+	Calibrated []Baseline `yaml:"-"`
+}
+
+// Baseline is the shape of the response to a request for content that doesn't exist. Responses with the same
+// shape are filtered out. A value of -1 means that part of the response changes between requests and isn't compared.
+// Step is the step of the request chain the baseline was learned from, it's only compared to responses of that step
+type Baseline struct {
+	Step    int
+	Code    int
+	Size    int
+	Words   int
+	Lines   int
+	Headers int
 }
 
 type TriggerFilterOptions struct {
-	Filters   FilterOptions
-	OnTrigger string
-	Requeue   bool
+	Filters   FilterOptions `yaml:",inline" flag:"t"`
+	OnTrigger string        `yaml:"on-trigger" flag:"ontrigger"`
+	Requeue   bool          `yaml:"requeue" flag:"trigger-requeue"`
 }
 
 type CaptureOptions struct {
-	Cap      string
-	CapGroup int
-	CapFile  string
+	Cap      string `yaml:"regex" flag:"capture"`
+	CapGroup int    `yaml:"group" flag:"capture-group"`
+	CapFile  string `yaml:"file" flag:"capture-file"`
 }
 
 type TransformOptions struct {
-	Transforms multiStringFlag
+	Transforms multiStringFlag `yaml:"transforms" flag:"transform"`
+}
+
+type ChainOptions struct {
+	Extract  multiStringFlag   `yaml:"extract" flag:"extract"`
+	When     multiStringFlag   `yaml:"when" flag:"when"`
+	Until    multiStringFlag   `yaml:"until" flag:"until"`
+	UntilMax int               `yaml:"until-max" flag:"until-max"`
+	AbortIf  multiStringFlag   `yaml:"abort-if" flag:"abort-if"`
+	HitStep  multiSplitIntFlag `yaml:"hit-step" flag:"hit-step"`
 }
 
 type OutputOptions struct {
-	Logger *utils.Logger
+	Logger      *utils.Logger       `yaml:"-"`
+	File        string              `yaml:"file" flag:"o"`
+	Format      string              `yaml:"format" flag:"of"`
+	Results     *utils.ResultWriter `yaml:"-"`
+	Report      *utils.Report       `yaml:"-"`
+	ReportFile  string              `yaml:"report" flag:"report"`
+	SaveDir     string              `yaml:"save-requests" flag:"save-requests"`
+	Saver       *utils.RequestSaver `yaml:"-"`
+	ReplayProxy string              `yaml:"replay-proxy" flag:"replay-proxy"`
+	Replayer    *utils.Replayer     `yaml:"-"`
 }
 
 type Args struct {
-	RequestOptions       RequestOptions
-	GeneralOptions       GeneralOptions
-	RecursionOptions     RecursionOptions
-	WordlistOptions      WordlistOptions
-	FilterOptions        FilterOptions
-	ErrorFilterOptions   FilterOptions
-	TriggerFilterOptions TriggerFilterOptions
-	CaptureOptions       CaptureOptions
-	TransformOptions     TransformOptions
-	OutputOptions        OutputOptions
+	RequestOptions       RequestOptions       `yaml:"request"`
+	GeneralOptions       GeneralOptions       `yaml:"general"`
+	RecursionOptions     RecursionOptions     `yaml:"recursion"`
+	WordlistOptions      WordlistOptions      `yaml:"wordlist"`
+	FilterOptions        FilterOptions        `yaml:"filter"`
+	ErrorFilterOptions   FilterOptions        `yaml:"error-filter" flag:"e"`
+	TriggerFilterOptions TriggerFilterOptions `yaml:"trigger-filter"`
+	CaptureOptions       CaptureOptions       `yaml:"capture"`
+	TransformOptions     TransformOptions     `yaml:"transform"`
+	ChainOptions         ChainOptions         `yaml:"chain"`
+	OutputOptions        OutputOptions        `yaml:"output"`
+}
+
+// ToYaml formats the options in the same layout as the config file. Secrets like proxy passwords, credential
+// headers and transform keys are hidden since the output ends up in reports
+func (a *Args) ToYaml() (string, error) {
+	out, err := yaml.Marshal(a.redacted())
+	return string(out), err
 }
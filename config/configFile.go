@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile returns the path of the user configuration file that is loaded when -config isn't supplied
+func DefaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gohammer", "config.yaml")
+}
+
+// LoadConfigFile reads a yaml configuration file into args. Top level keys in the file set the defaults, and
+// if a profile name is supplied the matching entry under the 'profiles' key is applied on top of them
+func LoadConfigFile(fname string, profile string, args *Args) error {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return fmt.Errorf("%s: %s", fname, err.Error())
+	}
+	// an empty file has nothing to apply
+	if len(doc.Content) <= 0 {
+		if profile != "" {
+			return fmt.Errorf("%s: profile %s not found", fname, profile)
+		}
+		return nil
+	}
+
+	root := doc.Content[0]
+	err = root.Decode(args)
+	if err != nil {
+		return fmt.Errorf("%s: %s", fname, err.Error())
+	}
+
+	if profile == "" {
+		return nil
+	}
+	profileNode := getProfile(root, profile)
+	if profileNode == nil {
+		return fmt.Errorf("%s: profile %s not found", fname, profile)
+	}
+	err = profileNode.Decode(args)
+	if err != nil {
+		return fmt.Errorf("%s: profile %s: %s", fname, profile, err.Error())
+	}
+	return nil
+}
+
+// getProfile finds the named profile under the 'profiles' key of a yaml mapping
+// Returns nil if the profile doesn't exist
+func getProfile(root *yaml.Node, profile string) *yaml.Node {
+	profiles := getMappingValue(root, "profiles")
+	if profiles == nil {
+		return nil
+	}
+	return getMappingValue(profiles, profile)
+}
+
+func getMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// OverrideWithFlags copies every option from src into dst whose command line flag name is in setFlags.
+// This is used to let flags supplied on the command line take priority over the values in a config file
+func OverrideWithFlags(dst *Args, src *Args, setFlags map[string]bool) {
+	overrideStruct(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem(), "", setFlags)
+}
+
+// overrideStruct walks the option structs using the flag struct tags. A flag tag on a nested struct is used
+// as a prefix for the flags inside it, for example the error filters use 'e' so Mc becomes 'emc'
+func overrideStruct(dst reflect.Value, src reflect.Value, prefix string, setFlags map[string]bool) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		name := field.Tag.Get("flag")
+		if field.Type.Kind() == reflect.Struct {
+			overrideStruct(dst.Field(i), src.Field(i), prefix+name, setFlags)
+		} else if name != "" && setFlags[prefix+name] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
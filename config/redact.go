@@ -0,0 +1,110 @@
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// what secrets are replaced with
+const redactedValue = "xxxxx"
+
+// the headers that carry credentials
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// fuzzing positions, transform positions and variables, which are safe to show since they're filled in at runtime
+var redactPositionRx = regexp.MustCompile(`@(t?\d+|\{\w+\})@`)
+
+// redacted copies the options with the secrets hidden
+func (a *Args) redacted() *Args {
+	redacted := *a
+	redacted.RequestOptions.Proxy = redactProxies(a.RequestOptions.Proxy)
+	redacted.OutputOptions.ReplayProxy = redactProxies(a.OutputOptions.ReplayProxy)
+	redacted.RequestOptions.Headers = redactHeaders(a.RequestOptions.Headers)
+	if a.RequestOptions.CookieFile != "" {
+		// the cookies are in the file, the directory it's in is nobody else's business
+		redacted.RequestOptions.CookieFile = filepath.Base(a.RequestOptions.CookieFile)
+	}
+	redacted.TransformOptions.Transforms = multiStringFlag{}
+	for _, transform := range a.TransformOptions.Transforms {
+		redacted.TransformOptions.Transforms = append(redacted.TransformOptions.Transforms, redactTransform(transform))
+	}
+	return &redacted
+}
+
+// redactProxies hides the passwords in a comma separated list of proxies, proxies without a scheme are http proxies
+func redactProxies(proxies string) string {
+	entries := strings.Split(proxies, ",")
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		prefix := ""
+		if !strings.Contains(entry, "://") {
+			prefix = "http://"
+		}
+		u, err := url.Parse(prefix + entry)
+		if err != nil || u.User == nil {
+			continue
+		}
+		entries[i] = strings.TrimPrefix(u.Redacted(), prefix)
+	}
+	return strings.Join(entries, ",")
+}
+
+// redactHeaders hides the values of the headers that carry credentials
+func redactHeaders(headers multiStringFlag) multiStringFlag {
+	redacted := multiStringFlag{}
+	for _, header := range headers {
+		name, _, found := strings.Cut(header, ":")
+		for _, secret := range secretHeaders {
+			if found && strings.EqualFold(strings.TrimSpace(name), secret) {
+				header = name + ": " + redactedValue
+			}
+		}
+		redacted = append(redacted, header)
+	}
+	return redacted
+}
+
+// redactTransform hides the literal arguments of a transform, like keys and secrets, while keeping the functions and
+// the positions so it's still clear what the transform does
+func redactTransform(transform string) string {
+	var out, literal strings.Builder
+	flush := func() {
+		value := literal.String()
+		literal.Reset()
+		// literals that are only positions and separators like @0@:@1@ aren't secret
+		if strings.TrimSpace(value) == "" || strings.Trim(redactPositionRx.ReplaceAllString(value, ""), " :;,.-_/|") == "" {
+			out.WriteString(value)
+			return
+		}
+		out.WriteString(redactedValue)
+	}
+	inLiteral := false
+	for i := 0; i < len(transform); i++ {
+		c := transform[i]
+		switch {
+		case c == '\\' && i+1 < len(transform):
+			literal.WriteByte(c)
+			literal.WriteByte(transform[i+1])
+			i++
+		case c == '`':
+			inLiteral = !inLiteral
+			literal.WriteByte(c)
+		case inLiteral:
+			literal.WriteByte(c)
+		case c == '(':
+			// the literal before a bracket is the name of the function
+			out.WriteString(literal.String())
+			literal.Reset()
+			out.WriteByte(c)
+		case c == ',' || c == ')':
+			flush()
+			out.WriteByte(c)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return out.String()
+}
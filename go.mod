@@ -1,3 +1,11 @@
 module github.com/Sceptre-Cybersec/gohammer
 
-go 1.23
\ No newline at end of file
+go 1.24.0
+
+require (
+	golang.org/x/crypto v0.42.0
+	golang.org/x/net v0.44.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.29.0 // indirect
@@ -0,0 +1,107 @@
+package request
+
+import (
+	"crypto/rand"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/Sceptre-Cybersec/gohammer/config"
+	"github.com/Sceptre-Cybersec/gohammer/processors/response"
+)
+
+// the lengths of the random words sent while calibrating, different lengths catch pages that reflect the request
+var calibrationLengths = []int{8, 12, 16, 24, 32}
+
+// Calibrate sends random words that shouldn't exist on the target through each request agent in order and
+// learns what the responses look like. Variables are extracted like in a normal run so later steps are sent the same way.
+// Returns a baseline for every response code that was seen at each step that can be a hit
+func Calibrate(agents []*ReqAgentHttp, numPositions int, args *config.Args) []config.Baseline {
+	responses := make([][]*response.Resp, len(agents))
+	for _, length := range calibrationLengths {
+		positions := []string{}
+		for range numPositions {
+			positions = append(positions, randomWord(length))
+		}
+		previousResponses := []response.Resp{}
+		for i, agent := range agents {
+			r, err := agent.Do(positions, args, &previousResponses)
+			if r == nil {
+				if err != nil {
+					args.OutputOptions.Logger.Printf("Error while calibrating: %s\n", err.Error())
+				}
+				break
+			}
+			err = response.ExtractVariables(r, i, args.ChainOptions.Extract)
+			if err != nil {
+				args.OutputOptions.Logger.Printf("Error while calibrating: %s\n", err.Error())
+				break
+			}
+			previousResponses = append(previousResponses, *r)
+			responses[i] = append(responses[i], r)
+		}
+	}
+
+	baselines := []config.Baseline{}
+	for step, agentResponses := range responses {
+		// the responses of steps that can't be hits are never filtered
+		if len(args.ChainOptions.HitStep) > 0 && !slices.Contains(args.ChainOptions.HitStep, step) {
+			continue
+		}
+		for _, b := range toBaselines(agentResponses) {
+			b.Step = step
+			baselines = append(baselines, b)
+		}
+	}
+	return baselines
+}
+
+// toBaselines groups the responses by code and marks the parts of the responses that change as -1
+func toBaselines(responses []*response.Resp) []config.Baseline {
+	baselines := []config.Baseline{}
+	codeIdx := map[int]int{}
+	for _, r := range responses {
+		values := config.Baseline{Code: r.Code, Size: r.Size, Words: r.Words, Lines: r.Lines, Headers: len(r.Headers)}
+		idx, ok := codeIdx[r.Code]
+		if !ok {
+			codeIdx[r.Code] = len(baselines)
+			baselines = append(baselines, values)
+			continue
+		}
+		b := &baselines[idx]
+		b.Size = mergeBaselineValue(b.Size, values.Size)
+		b.Words = mergeBaselineValue(b.Words, values.Words)
+		b.Lines = mergeBaselineValue(b.Lines, values.Lines)
+		b.Headers = mergeBaselineValue(b.Headers, values.Headers)
+	}
+
+	// a baseline that only compares the response code would filter out everything with that code
+	usable := []config.Baseline{}
+	for _, b := range baselines {
+		if b.Size != -1 || b.Words != -1 || b.Lines != -1 {
+			usable = append(usable, b)
+		}
+	}
+	return usable
+}
+
+func mergeBaselineValue(current int, value int) int {
+	if current != value {
+		return -1
+	}
+	return current
+}
+
+// randomWord generates a random lowercase word of the given length
+func randomWord(length int) string {
+	alphabet := "abcdefghijklmnopqrstuvwxyz0123456789"
+	var word strings.Builder
+	for range length {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return word.String()
+		}
+		word.WriteByte(alphabet[idx.Int64()])
+	}
+	return word.String()
+}
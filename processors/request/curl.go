@@ -0,0 +1,435 @@
+package request
+
+import (
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// the short curl options that take a value
+const curlShortValues = "AbcCdDeEFHKmoPQrTtuUwxXyYz"
+
+// the long curl options that take a value but don't change the request
+var curlIgnoredValues = map[string]bool{
+	"--output": true, "--proxy": true, "--proxy-user": true, "--max-time": true, "--connect-timeout": true,
+	"--write-out": true, "--cacert": true, "--capath": true, "--cert": true, "--key": true, "--cookie-jar": true,
+	"--dump-header": true, "--retry": true, "--retry-delay": true, "--retry-max-time": true, "--limit-rate": true,
+	"--resolve": true, "--connect-to": true, "--max-redirs": true, "--interface": true, "--config": true,
+	"--range": true, "--continue-at": true, "--cert-type": true, "--key-type": true, "--ciphers": true,
+	"--trace": true, "--trace-ascii": true, "--stderr": true, "--user-agent-file": true, "--proto": true,
+	"--proto-redir": true, "--time-cond": true, "--speed-limit": true, "--speed-time": true,
+}
+
+// the long curl options with a short equivalent
+var curlLongOptions = map[string]string{
+	"--request": "-X", "--header": "-H", "--data": "-d", "--data-ascii": "-d", "--cookie": "-b",
+	"--user-agent": "-A", "--referer": "-e", "--user": "-u", "--get": "-G", "--head": "-I", "--form": "-F",
+	"--upload-file": "-T",
+}
+
+// splitShell splits a bash command line into words the way the shell would, with quotes, escapes and line
+// continuations. Unquoted newlines, ; and && are returned as a separate ";" word
+func splitShell(input string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+		}
+		word.Reset()
+		inWord = false
+	}
+	separate := func() {
+		endWord()
+		if len(words) > 0 && words[len(words)-1] != ";" {
+			words = append(words, ";")
+		}
+	}
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			i++
+			if i >= len(runes) {
+				break
+			}
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+			}
+			if runes[i] == '\n' {
+				// line continuation
+				continue
+			}
+			word.WriteRune(runes[i])
+			inWord = true
+		case c == '\'':
+			end := slices.Index(runes[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unclosed ' in command")
+			}
+			word.WriteString(string(runes[i+1 : i+1+end]))
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			// ansi-c quoting, used by browsers for bodies with special characters
+			i += 2
+			closed := false
+			for ; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					closed = true
+					break
+				}
+				if runes[i] != '\\' || i+1 >= len(runes) {
+					word.WriteRune(runes[i])
+					continue
+				}
+				i++
+				i += writeAnsiEscape(&word, runes, i)
+			}
+			if !closed {
+				return nil, errors.New("unclosed $' in command")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			closed := false
+			for ; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, errors.New("unclosed \" in command")
+			}
+			inWord = true
+		case c == '#' && !inWord:
+			// comments run to the end of the line
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case c == '\n' || c == ';':
+			separate()
+		case c == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			i++
+			separate()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	endWord()
+	return words, nil
+}
+
+// the escape sequences in $'...' strings that stand for a single character
+var ansiEscapes = map[rune]string{'n': "\n", 'r': "\r", 't': "\t", 'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b",
+	'f': "\f", 'v': "\v", '\\': "\\", '\'': "'", '"': "\"", '?': "?"}
+
+// writeAnsiEscape writes the character for the escape sequence at i in a $'...' string, which is the character after
+// the \, and returns how many more characters the sequence used
+func writeAnsiEscape(word *strings.Builder, runes []rune, i int) int {
+	if s, ok := ansiEscapes[runes[i]]; ok {
+		word.WriteString(s)
+		return 0
+	}
+	start, base, maxLen := i+1, 16, 0
+	switch {
+	case runes[i] == 'x':
+		maxLen = 2
+	case runes[i] == 'u':
+		maxLen = 4
+	case runes[i] == 'U':
+		maxLen = 8
+	case runes[i] >= '0' && runes[i] <= '7':
+		start, base, maxLen = i, 8, 3
+	}
+	end := start
+	for end < len(runes) && end-start < maxLen {
+		if _, err := strconv.ParseUint(string(runes[end]), base, 8); err != nil {
+			break
+		}
+		end++
+	}
+	n, err := strconv.ParseUint(string(runes[start:end]), base, 32)
+	if err != nil {
+		word.WriteRune('\\')
+		word.WriteRune(runes[i])
+		return 0
+	}
+	if runes[i] == 'u' || runes[i] == 'U' {
+		word.WriteRune(rune(n))
+	} else {
+		// \x and octal escapes are bytes, so they can be used for binary data
+		word.WriteByte(byte(n))
+	}
+	return end - i - 1
+}
+
+// ParseCurl reads the requests from curl commands, like the ones copied from the network tab of a browser. Commands
+// can be split over several lines with \ and there can be more than one command
+func ParseCurl(command string) ([]ImportedRequest, error) {
+	words, err := splitShell(command)
+	if err != nil {
+		return nil, err
+	}
+	reqs := []ImportedRequest{}
+	start := 0
+	for i := 0; i <= len(words); i++ {
+		if i < len(words) && words[i] != ";" {
+			continue
+		}
+		cmd := words[start:i]
+		start = i + 1
+		if len(cmd) <= 0 || (cmd[0] != "curl" && !strings.HasSuffix(cmd[0], "/curl") && cmd[0] != "curl.exe") {
+			continue
+		}
+		cmdReqs, err := parseCurlArgs(cmd[1:])
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, cmdReqs...)
+	}
+	if len(reqs) <= 0 {
+		return nil, errors.New("no curl commands found")
+	}
+	return reqs, nil
+}
+
+// curlOption is an option from a curl command, long options are changed to their short version if there is one
+type curlOption struct {
+	name  string
+	value string
+}
+
+// splitCurlArgs separates the options from the urls
+func splitCurlArgs(args []string) ([]curlOption, []string, error) {
+	options := []curlOption{}
+	urls := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			urls = append(urls, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name := arg
+			if short, ok := curlLongOptions[arg]; ok {
+				name = short
+			}
+			takesValue := curlIgnoredValues[arg] || (len(name) == 2 && strings.Contains(curlShortValues, name[1:]))
+			switch arg {
+			case "--data-raw", "--data-binary", "--data-urlencode", "--json", "--url", "--oauth2-bearer", "--url-query", "--form-string":
+				takesValue = true
+			}
+			option := curlOption{name: name}
+			if takesValue {
+				i++
+				if i >= len(args) {
+					return nil, nil, fmt.Errorf("%s is missing a value", arg)
+				}
+				option.value = args[i]
+			}
+			if name == "--url" {
+				urls = append(urls, option.value)
+				continue
+			}
+			options = append(options, option)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// short options can be grouped like -sSL and the value can follow the option like -XPOST
+			for j := 1; j < len(arg); j++ {
+				name := "-" + arg[j:j+1]
+				if !strings.Contains(curlShortValues, arg[j:j+1]) {
+					options = append(options, curlOption{name: name})
+					continue
+				}
+				value := arg[j+1:]
+				if value == "" {
+					i++
+					if i >= len(args) {
+						return nil, nil, fmt.Errorf("%s is missing a value", name)
+					}
+					value = args[i]
+				}
+				options = append(options, curlOption{name: name, value: value})
+				break
+			}
+		default:
+			urls = append(urls, arg)
+		}
+	}
+	return options, urls, nil
+}
+
+// curlData gets the data sent by a data option, reading it from a file if it starts with @
+func curlData(option curlOption) (string, error) {
+	value := option.value
+	switch option.name {
+	case "--data-raw", "--json":
+		return value, nil
+	case "--data-urlencode":
+		name, content, found := strings.Cut(value, "=")
+		if !found {
+			return url.QueryEscape(value), nil
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	fileBytes, err := os.ReadFile(value[1:])
+	if err != nil {
+		return "", fmt.Errorf("couldn't read data file %s", value[1:])
+	}
+	data := string(fileBytes)
+	if option.name == "-d" {
+		// curl strips the newlines from text data files
+		data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+	}
+	return data, nil
+}
+
+func hasHeader(headers []string, name string) bool {
+	for _, header := range headers {
+		headerName, _, _ := strings.Cut(header, ":")
+		if strings.EqualFold(strings.TrimSpace(headerName), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCurlArgs builds the requests for the arguments of one curl command, one for each url
+func parseCurlArgs(args []string) ([]ImportedRequest, error) {
+	options, urls, err := splitCurlArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) <= 0 {
+		return nil, errors.New("no url in curl command")
+	}
+	method := ""
+	headers := []string{}
+	cookies := []string{}
+	data := []string{}
+	query := []string{}
+	get := false
+	head := false
+	json := false
+	for _, option := range options {
+		switch option.name {
+		case "-X":
+			method = option.value
+		case "-H":
+			name, value, found := strings.Cut(option.value, ":")
+			// "Name:" removes a header in curl and "Name;" sends it empty
+			if strings.HasSuffix(option.value, ";") && !found {
+				headers = append(headers, strings.TrimSuffix(option.value, ";")+": ")
+			} else if found && strings.TrimSpace(value) != "" {
+				headers = append(headers, name+": "+strings.TrimSpace(value))
+			}
+		case "-d", "--data-raw", "--data-binary", "--data-urlencode", "--json":
+			value, err := curlData(option)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, value)
+			json = json || option.name == "--json"
+		case "-b":
+			if !strings.Contains(option.value, "=") {
+				return nil, fmt.Errorf("curl cookie file %s isn't supported, use -cookie-file instead", option.value)
+			}
+			cookies = append(cookies, option.value)
+		case "-A":
+			headers = append(headers, "User-Agent: "+option.value)
+		case "-e":
+			headers = append(headers, "Referer: "+option.value)
+		case "-u":
+			headers = append(headers, "Authorization: Basic "+b64.StdEncoding.EncodeToString([]byte(option.value)))
+		case "--oauth2-bearer":
+			headers = append(headers, "Authorization: Bearer "+option.value)
+		case "--url-query":
+			// encoded like --data-urlencode, unless it starts with + to be sent as is
+			if strings.HasPrefix(option.value, "+") {
+				query = append(query, option.value[1:])
+				continue
+			}
+			value, err := curlData(curlOption{name: "--data-urlencode", value: option.value})
+			if err != nil {
+				return nil, err
+			}
+			query = append(query, value)
+		case "-G":
+			get = true
+		case "-I":
+			head = true
+		case "-F", "--form-string", "-T":
+			return nil, fmt.Errorf("curl option %s isn't supported", option.name)
+		}
+	}
+	if len(cookies) > 0 {
+		headers = append(headers, "Cookie: "+strings.Join(cookies, "; "))
+	}
+	body := ""
+	if json {
+		body = strings.Join(data, "")
+		if !hasHeader(headers, "Content-Type") {
+			headers = append(headers, "Content-Type: application/json")
+		}
+		if !hasHeader(headers, "Accept") {
+			headers = append(headers, "Accept: application/json")
+		}
+	} else if get {
+		query = append(query, data...)
+	} else if len(data) > 0 {
+		body = strings.Join(data, "&")
+		if !hasHeader(headers, "Content-Type") {
+			headers = append(headers, "Content-Type: application/x-www-form-urlencoded")
+		}
+	}
+	if method == "" {
+		switch {
+		case head:
+			method = "HEAD"
+		case body != "":
+			method = "POST"
+		default:
+			method = "GET"
+		}
+	}
+	reqs := []ImportedRequest{}
+	for _, reqUrl := range urls {
+		// curl uses http for urls without a scheme
+		if !strings.Contains(reqUrl, "://") {
+			reqUrl = "http://" + reqUrl
+		}
+		if len(query) > 0 {
+			separator := "?"
+			if strings.Contains(reqUrl, "?") {
+				separator = "&"
+			}
+			reqUrl += separator + strings.Join(query, "&")
+		}
+		reqs = append(reqs, ImportedRequest{Method: method, Url: reqUrl, Headers: headers, Body: body})
+	}
+	return reqs, nil
+}
@@ -0,0 +1,196 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ImportedRequest is a request template read from a HAR export, a curl command or an OpenAPI spec
+type ImportedRequest struct {
+	Method  string
+	Url     string
+	Headers []string
+	Body    string
+}
+
+// splitUrl splits a url into the scheme and host, and the path with the query. Urls without a scheme are all path
+func splitUrl(reqUrl string) (string, string) {
+	schemeEnd := strings.Index(reqUrl, "://")
+	if schemeEnd < 0 {
+		return "", reqUrl
+	}
+	pathStart := strings.IndexAny(reqUrl[schemeEnd+3:], "/?#")
+	if pathStart < 0 {
+		return reqUrl, "/"
+	}
+	pathStart += schemeEnd + 3
+	path := reqUrl[pathStart:]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return reqUrl[:pathStart], path
+}
+
+// HasHost checks if the request has a full url, requests without one need the url base from -u
+func (r *ImportedRequest) HasHost() bool {
+	base, _ := splitUrl(r.Url)
+	return base != ""
+}
+
+// the fuzzing positions filled in from the wordlists
+var importedPositionRx = regexp.MustCompile(`@(\d+)@`)
+
+// Positions gets the fuzzing positions used anywhere in the request in order, like @0@ @1@
+func (r *ImportedRequest) Positions() []string {
+	numbers := []int{}
+	for _, match := range importedPositionRx.FindAllStringSubmatch(r.ToString(), -1) {
+		n, _ := strconv.Atoi(match[1])
+		if !slices.Contains(numbers, n) {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+	positions := []string{}
+	for _, n := range numbers {
+		positions = append(positions, "@"+strconv.Itoa(n)+"@")
+	}
+	return positions
+}
+
+// ToString formats the request like a request file
+func (r *ImportedRequest) ToString() string {
+	base, path := splitUrl(r.Url)
+	lines := []string{r.Method + " " + path + " HTTP/1.1"}
+	hasHost := false
+	for _, header := range r.Headers {
+		name, _, _ := strings.Cut(header, ":")
+		hasHost = hasHost || strings.EqualFold(name, "Host")
+	}
+	if !hasHost && base != "" {
+		lines = append(lines, "Host: "+base[strings.Index(base, "://")+3:])
+	}
+	lines = append(lines, r.Headers...)
+	return strings.Join(lines, "\r\n") + "\r\n\r\n" + r.Body
+}
+
+// ToRequestAgent creates the agent for the request, the url base replaces the scheme and host of the request if it
+// is supplied
+func (r *ImportedRequest) ToRequestAgent(urlBase string, proxy string, timeout int, removeHeaders []string, respectEscapeChars bool) *ReqAgentHttp {
+	base, path := splitUrl(r.Url)
+	if urlBase != "" {
+		base = urlBase
+	}
+	return NewReqAgentHttp(base+path, r.Method, filterHeaders(r.Headers, removeHeaders), r.Body, proxy, timeout, respectEscapeChars)
+}
+
+// ToRawRequestAgent creates an agent that sends the request exactly as ToString formats it
+func (r *ImportedRequest) ToRawRequestAgent(urlBase string, respectEscapeChars bool) *ReqAgentHttp {
+	base, _ := splitUrl(r.Url)
+	if urlBase != "" {
+		base = urlBase
+	}
+	return FileToRawRequestAgent(r.ToString(), base, false, respectEscapeChars)
+}
+
+// FilterImported keeps the requests where the method and url, like "POST https://some.site/login", match the regex
+func FilterImported(reqs []ImportedRequest, match string) ([]ImportedRequest, error) {
+	if match == "" {
+		return reqs, nil
+	}
+	re, err := regexp.Compile(match)
+	if err != nil {
+		return nil, err
+	}
+	filtered := []ImportedRequest{}
+	for _, req := range reqs {
+		if re.MatchString(req.Method + " " + req.Url) {
+			filtered = append(filtered, req)
+		}
+	}
+	return filtered, nil
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string         `json:"method"`
+				Url      string         `json:"url"`
+				Headers  []harNameValue `json:"headers"`
+				PostData *struct {
+					MimeType string         `json:"mimeType"`
+					Text     string         `json:"text"`
+					Params   []harNameValue `json:"params"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ParseHar reads the requests from a HAR export, like the ones saved from the network tab of a browser
+func ParseHar(content []byte) ([]ImportedRequest, error) {
+	var har harFile
+	err := json.Unmarshal(content, &har)
+	if err != nil {
+		return nil, fmt.Errorf("invalid har file (%s)", err.Error())
+	}
+	reqs := []ImportedRequest{}
+	for _, entry := range har.Log.Entries {
+		harReq := entry.Request
+		if harReq.Method == "" || harReq.Url == "" {
+			continue
+		}
+		req := ImportedRequest{Method: harReq.Method, Url: harReq.Url}
+		for _, header := range harReq.Headers {
+			// pseudo-headers from http2 are set from the url instead
+			if strings.HasPrefix(header.Name, ":") {
+				continue
+			}
+			req.Headers = append(req.Headers, header.Name+": "+header.Value)
+		}
+		if harReq.PostData != nil {
+			req.Body = harReq.PostData.Text
+			if req.Body == "" {
+				params := []string{}
+				for _, param := range harReq.PostData.Params {
+					params = append(params, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+				}
+				req.Body = strings.Join(params, "&")
+			}
+		}
+		reqs = append(reqs, req)
+	}
+	if len(reqs) <= 0 {
+		return nil, errors.New("no requests found in har file")
+	}
+	return reqs, nil
+}
+
+// filterHeaders removes the headers that start with one of the removed headers
+func filterHeaders(headers []string, removeHeaders []string) []string {
+	var cleanedHeaders []string
+	for _, header := range headers {
+		skip := false
+		for _, removeHeader := range removeHeaders {
+			if strings.HasPrefix(header, removeHeader) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			cleanedHeaders = append(cleanedHeaders, header)
+		}
+	}
+	return cleanedHeaders
+}
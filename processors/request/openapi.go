@@ -0,0 +1,275 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the operations that can be listed under a path
+var openApiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openApiSpec is an OpenAPI 3 or Swagger 2 document in json or yaml. The yaml nodes are used so paths, parameters
+// and properties stay in the order they were written
+type openApiSpec struct {
+	root *yaml.Node
+}
+
+// yamlValue gets the value of a key in a yaml mapping, or nil if it isn't there
+func yamlValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlString gets the value of a key in a yaml mapping as a string
+func yamlString(node *yaml.Node, key string) string {
+	value := yamlValue(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return value.Value
+}
+
+// resolve follows $ref links inside the document like #/components/schemas/User
+func (s *openApiSpec) resolve(node *yaml.Node) *yaml.Node {
+	for range 32 {
+		ref := yamlString(node, "$ref")
+		if !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		node = s.root
+		for _, key := range strings.Split(ref[2:], "/") {
+			key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+			node = yamlValue(node, key)
+		}
+	}
+	return node
+}
+
+// baseUrl gets the url of the first server, or builds it from the host for Swagger 2
+func (s *openApiSpec) baseUrl() string {
+	if servers := yamlValue(s.root, "servers"); servers != nil && len(servers.Content) > 0 {
+		server := servers.Content[0]
+		base := yamlString(server, "url")
+		variables := yamlValue(server, "variables")
+		for i := 0; variables != nil && i+1 < len(variables.Content); i += 2 {
+			base = strings.ReplaceAll(base, "{"+variables.Content[i].Value+"}", yamlString(variables.Content[i+1], "default"))
+		}
+		return strings.TrimSuffix(base, "/")
+	}
+	host := yamlString(s.root, "host")
+	base := strings.TrimSuffix(yamlString(s.root, "basePath"), "/")
+	if host == "" {
+		return base
+	}
+	scheme := "https"
+	if schemes := yamlValue(s.root, "schemes"); schemes != nil && len(schemes.Content) > 0 {
+		scheme = schemes.Content[0].Value
+	}
+	return scheme + "://" + host + base
+}
+
+// openApiPositions hands out the fuzzing positions for the parameters of an operation
+type openApiPositions struct {
+	next int
+}
+
+func (p *openApiPositions) take() string {
+	position := fmt.Sprintf("@%d@", p.next)
+	p.next++
+	return position
+}
+
+// schemaTemplate builds a json template for a schema with a fuzzing position for every value. Strings are quoted,
+// other values are left for the wordlist to fill in
+func (s *openApiSpec) schemaTemplate(schema *yaml.Node, positions *openApiPositions, depth int) string {
+	schema = s.resolve(schema)
+	if depth > 8 {
+		return `"` + positions.take() + `"`
+	}
+	if allOf := yamlValue(schema, "allOf"); allOf != nil {
+		merged := &yaml.Node{Kind: yaml.MappingNode}
+		properties := &yaml.Node{Kind: yaml.MappingNode}
+		for _, part := range allOf.Content {
+			if partProperties := yamlValue(s.resolve(part), "properties"); partProperties != nil {
+				properties.Content = append(properties.Content, partProperties.Content...)
+			}
+		}
+		merged.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Value: "properties"}, properties}
+		schema = merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if options := yamlValue(schema, key); options != nil && len(options.Content) > 0 {
+			return s.schemaTemplate(options.Content[0], positions, depth+1)
+		}
+	}
+	properties := yamlValue(schema, "properties")
+	switch schemaType := yamlString(schema, "type"); {
+	case properties != nil:
+		fields := []string{}
+		for i := 0; i+1 < len(properties.Content); i += 2 {
+			name, _ := json.Marshal(properties.Content[i].Value)
+			fields = append(fields, string(name)+":"+s.schemaTemplate(properties.Content[i+1], positions, depth+1))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	case schemaType == "object":
+		return "{}"
+	case schemaType == "array":
+		return "[" + s.schemaTemplate(yamlValue(schema, "items"), positions, depth+1) + "]"
+	case schemaType == "integer" || schemaType == "number" || schemaType == "boolean":
+		return positions.take()
+	}
+	return `"` + positions.take() + `"`
+}
+
+// formTemplate builds a url encoded form with a fuzzing position for every property of the schema
+func (s *openApiSpec) formTemplate(schema *yaml.Node, positions *openApiPositions) string {
+	properties := yamlValue(s.resolve(schema), "properties")
+	fields := []string{}
+	for i := 0; properties != nil && i+1 < len(properties.Content); i += 2 {
+		fields = append(fields, url.QueryEscape(properties.Content[i].Value)+"="+positions.take())
+	}
+	return strings.Join(fields, "&")
+}
+
+// requestBody builds the body of an OpenAPI 3 operation, json is used if the operation accepts it
+func (s *openApiSpec) requestBody(operation *yaml.Node, positions *openApiPositions) (string, string) {
+	content := yamlValue(s.resolve(yamlValue(operation, "requestBody")), "content")
+	if content == nil || len(content.Content) < 2 {
+		return "", ""
+	}
+	contentType := content.Content[0].Value
+	media := content.Content[1]
+	for i := 0; i+1 < len(content.Content); i += 2 {
+		if strings.Contains(content.Content[i].Value, "json") {
+			contentType = content.Content[i].Value
+			media = content.Content[i+1]
+			break
+		}
+	}
+	schema := yamlValue(media, "schema")
+	switch {
+	case strings.Contains(contentType, "json"):
+		return contentType, s.schemaTemplate(schema, positions, 0)
+	case contentType == "application/x-www-form-urlencoded":
+		return contentType, s.formTemplate(schema, positions)
+	}
+	return contentType, positions.take()
+}
+
+// operation builds the request template for an operation, every parameter gets its own fuzzing position starting
+// from @0@ in the order they're listed
+func (s *openApiSpec) operation(base string, path string, method string, pathParameters []*yaml.Node, operation *yaml.Node) ImportedRequest {
+	opParameters := []*yaml.Node{}
+	if listed := yamlValue(operation, "parameters"); listed != nil {
+		opParameters = listed.Content
+	}
+	// parameters of the operation replace the ones with the same name from the path
+	parameters := []*yaml.Node{}
+	for _, pathParameter := range pathParameters {
+		pathParameter = s.resolve(pathParameter)
+		replaced := slices.ContainsFunc(opParameters, func(opParameter *yaml.Node) bool {
+			opParameter = s.resolve(opParameter)
+			return yamlString(opParameter, "name") == yamlString(pathParameter, "name") && yamlString(opParameter, "in") == yamlString(pathParameter, "in")
+		})
+		if !replaced {
+			parameters = append(parameters, pathParameter)
+		}
+	}
+	parameters = append(parameters, opParameters...)
+	positions := &openApiPositions{}
+	reqPath := path
+	query := []string{}
+	headers := []string{}
+	cookies := []string{}
+	form := []string{}
+	body := ""
+	contentType := ""
+	for _, parameter := range parameters {
+		parameter = s.resolve(parameter)
+		name := yamlString(parameter, "name")
+		switch yamlString(parameter, "in") {
+		case "path":
+			reqPath = strings.ReplaceAll(reqPath, "{"+name+"}", positions.take())
+		case "query":
+			query = append(query, url.QueryEscape(name)+"="+positions.take())
+		case "header":
+			headers = append(headers, name+": "+positions.take())
+		case "cookie":
+			cookies = append(cookies, name+"="+positions.take())
+		case "formData":
+			form = append(form, url.QueryEscape(name)+"="+positions.take())
+		case "body":
+			contentType = "application/json"
+			body = s.schemaTemplate(yamlValue(parameter, "schema"), positions, 0)
+		}
+	}
+	if len(form) > 0 {
+		contentType = "application/x-www-form-urlencoded"
+		body = strings.Join(form, "&")
+	}
+	if bodyType, bodyTemplate := s.requestBody(operation, positions); bodyType != "" {
+		contentType, body = bodyType, bodyTemplate
+	}
+	if len(cookies) > 0 {
+		headers = append(headers, "Cookie: "+strings.Join(cookies, "; "))
+	}
+	if contentType != "" {
+		headers = append(headers, "Content-Type: "+contentType)
+	}
+	if len(query) > 0 {
+		reqPath += "?" + strings.Join(query, "&")
+	}
+	return ImportedRequest{Method: strings.ToUpper(method), Url: base + reqPath, Headers: headers, Body: body}
+}
+
+// ParseOpenApi builds a request for every operation in an OpenAPI 3 or Swagger 2 spec. The path, query, header,
+// cookie and body parameters of each operation become fuzzing positions starting from @0@
+func ParseOpenApi(content []byte) ([]ImportedRequest, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi spec (%s)", err.Error())
+	}
+	if len(doc.Content) <= 0 {
+		return nil, errors.New("empty openapi spec")
+	}
+	spec := &openApiSpec{root: doc.Content[0]}
+	paths := yamlValue(spec.root, "paths")
+	if paths == nil {
+		return nil, errors.New("no paths found in openapi spec")
+	}
+	base := spec.baseUrl()
+	reqs := []ImportedRequest{}
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		path := paths.Content[i].Value
+		item := spec.resolve(paths.Content[i+1])
+		pathParameters := []*yaml.Node{}
+		if parameters := yamlValue(item, "parameters"); parameters != nil {
+			pathParameters = parameters.Content
+		}
+		for j := 0; item != nil && j+1 < len(item.Content); j += 2 {
+			method := item.Content[j].Value
+			if !slices.Contains(openApiMethods, method) {
+				continue
+			}
+			reqs = append(reqs, spec.operation(base, path, method, pathParameters, item.Content[j+1]))
+		}
+	}
+	if len(reqs) <= 0 {
+		return nil, errors.New("no operations found in openapi spec")
+	}
+	return reqs, nil
+}
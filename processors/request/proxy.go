@@ -0,0 +1,243 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the number of failed requests in a row before a proxy is dropped, and how long it is dropped for
+const (
+	proxyMaxFailures = 3
+	proxyCooldown    = 30 * time.Second
+)
+
+// UpstreamProxy is a single proxy from the proxy pool along with its health
+type UpstreamProxy struct {
+	Url            *url.URL
+	client         *http.Client
+	http2Client    *http.Client
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// ProxyPool rotates requests through a list of proxies and temporarily drops proxies that keep failing
+type ProxyPool struct {
+	proxies []*UpstreamProxy
+	next    int
+	lock    sync.Mutex
+}
+
+// the pools are shared by all request agents using the same proxy option so the health of a proxy is tracked
+// across the whole request chain
+var proxyPools = map[string]*ProxyPool{}
+var proxyPoolsLock sync.Mutex
+
+// getProxyPool returns the pool for the proxy option, creating it the first time it's used
+func getProxyPool(proxy string, timeout int) *ProxyPool {
+	proxyPoolsLock.Lock()
+	defer proxyPoolsLock.Unlock()
+	if pool, ok := proxyPools[proxy]; ok {
+		return pool
+	}
+	urls, err := ParseProxies(proxy)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	pool := &ProxyPool{}
+	for _, u := range urls {
+		pool.proxies = append(pool.proxies, &UpstreamProxy{
+			Url:         u,
+			client:      newHttpClient(timeout, u, false),
+			http2Client: newHttpClient(timeout, u, true),
+		})
+	}
+	proxyPools[proxy] = pool
+	return pool
+}
+
+// ParseProxies reads the proxy option, which is either a file with one proxy per line or a comma separated list of
+// proxies. Proxies without a scheme are treated as http proxies
+func ParseProxies(proxy string) ([]*url.URL, error) {
+	var entries []string
+	if info, err := os.Stat(proxy); err == nil && !info.IsDir() {
+		f, err := os.Open(proxy)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				entries = append(entries, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, entry := range strings.Split(proxy, ",") {
+			if strings.TrimSpace(entry) != "" {
+				entries = append(entries, strings.TrimSpace(entry))
+			}
+		}
+	}
+	if len(entries) <= 0 {
+		return nil, fmt.Errorf("no proxies found in %s", proxy)
+	}
+
+	urls := []*url.URL{}
+	for _, entry := range entries {
+		if !strings.Contains(entry, "://") {
+			entry = "http://" + entry
+		}
+		u, err := url.Parse(entry)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %s", entry)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks4", "socks4a", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy type %s in %s", u.Scheme, entry)
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// Next picks the proxy for the next request, either round robin (rr) or randomly (random).
+// Unhealthy proxies are skipped unless every proxy is unhealthy, then the one that recovers first is used
+func (p *ProxyPool) Next(mode string) *UpstreamProxy {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	healthy := []int{}
+	for i, proxy := range p.proxies {
+		if !now.Before(proxy.unhealthyUntil) {
+			healthy = append(healthy, i)
+		}
+	}
+	if len(healthy) <= 0 {
+		soonest := p.proxies[0]
+		for _, proxy := range p.proxies {
+			if proxy.unhealthyUntil.Before(soonest.unhealthyUntil) {
+				soonest = proxy
+			}
+		}
+		return soonest
+	}
+	if mode == "random" {
+		return p.proxies[healthy[rand.Intn(len(healthy))]]
+	}
+	// round robin, continue from the last proxy that was used
+	for _, i := range healthy {
+		if i >= p.next {
+			p.next = i + 1
+			return p.proxies[i]
+		}
+	}
+	p.next = healthy[0] + 1
+	return p.proxies[healthy[0]]
+}
+
+// Success resets the failure count of a proxy
+func (p *ProxyPool) Success(proxy *UpstreamProxy) {
+	p.lock.Lock()
+	proxy.failures = 0
+	p.lock.Unlock()
+}
+
+// Failure records a failed request, after too many failures in a row the proxy is dropped for a while
+func (p *ProxyPool) Failure(proxy *UpstreamProxy) {
+	p.lock.Lock()
+	proxy.failures++
+	if proxy.failures >= proxyMaxFailures {
+		proxy.failures = 0
+		proxy.unhealthyUntil = time.Now().Add(proxyCooldown)
+	}
+	p.lock.Unlock()
+}
+
+// dialSocks4 opens a connection to addr through a socks4 or socks4a proxy. The user of the proxy url is sent as
+// the socks user id
+func dialSocks4(ctx context.Context, proxyUrl *url.URL, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// socks4 only supports ipv4 addresses, socks4a lets the proxy resolve the host name
+	ip := net.ParseIP(host).To4()
+	if ip == nil && proxyUrl.Scheme == "socks4" {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if ip = a.IP.To4(); ip != nil {
+				break
+			}
+		}
+		if ip == nil {
+			return nil, fmt.Errorf("socks4: no ipv4 address for %s", host)
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyUrl.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := []byte{4, 1, 0, 0}
+	binary.BigEndian.PutUint16(req[2:], uint16(port))
+	if ip == nil {
+		req = append(req, 0, 0, 0, 1)
+	} else {
+		req = append(req, ip...)
+	}
+	req = append(req, []byte(proxyUrl.User.Username())...)
+	req = append(req, 0)
+	if ip == nil {
+		req = append(req, []byte(host)...)
+		req = append(req, 0)
+	}
+	_, err = conn.Write(req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply := make([]byte, 8)
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply[1] != 0x5a {
+		conn.Close()
+		return nil, fmt.Errorf("socks4: proxy %s rejected the connection to %s (code %d)", proxyUrl.Host, addr, reply[1])
+	}
+	return conn, nil
+}
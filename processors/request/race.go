@@ -0,0 +1,349 @@
+package request
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sceptre-Cybersec/gohammer/config"
+	"github.com/Sceptre-Cybersec/gohammer/processors/response"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// headers that aren't allowed in http2 requests
+var http2SkipHeaders = []string{"connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade", "host"}
+
+// RaceResult is the outcome of a single request sent in a race
+type RaceResult struct {
+	Resp *response.Resp
+	Err  error
+	// how long after the requests were released the response arrived
+	Arrival time.Duration
+}
+
+// Race sends count copies of the request at the same time to test for race conditions. Everything except the end of
+// each request is sent first and then the rest of every request is released at once, using last-byte sync over
+// http1.1 or a single packet holding the final frame of every stream over http2.
+// Returns the results in the order the requests were prepared
+func (req *ReqAgentHttp) Race(positions []string, count int, args *config.Args) []RaceResult {
+	procReqs := []*ReqTemplate{}
+	for range count {
+		previousResponses := []response.Resp{}
+		procReqs = append(procReqs, procReqTemplate(req, positions, args, &previousResponses))
+	}
+	var results []RaceResult
+	if args.RequestOptions.Http2 {
+		results = raceHttp2(procReqs, args)
+	} else {
+		results = raceHttp1(procReqs, req.raw, args)
+	}
+	for i := range results {
+		if results[i].Resp != nil {
+			results[i].Resp.Request = newReqInfo(procReqs[i], &[]response.Resp{})
+			results[i].Resp.Request.Raw = req.raw
+		}
+	}
+	return results
+}
+
+// RaceSpread returns the time between the first and the last response of a race
+func RaceSpread(results []RaceResult) time.Duration {
+	var first, last time.Duration
+	found := false
+	for _, r := range results {
+		if r.Resp == nil {
+			continue
+		}
+		if !found || r.Arrival < first {
+			first = r.Arrival
+		}
+		if !found || r.Arrival > last {
+			last = r.Arrival
+		}
+		found = true
+	}
+	return last - first
+}
+
+// raceHttp1 opens a connection for every request and sends all but the last byte of each request, then sends the
+// last bytes together
+func raceHttp1(procReqs []*ReqTemplate, raw bool, args *config.Args) []RaceResult {
+	results := make([]RaceResult, len(procReqs))
+	conns := make([]net.Conn, len(procReqs))
+	payloads := make([][]byte, len(procReqs))
+	for i, procReq := range procReqs {
+		if raw {
+			payloads[i] = []byte(procReq.body)
+		} else {
+			var buf bytes.Buffer
+			err := buildHttpRequest(procReq, args).Write(&buf)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			payloads[i] = buf.Bytes()
+		}
+		if len(payloads[i]) <= 0 {
+			results[i].Err = errors.New("empty request")
+			continue
+		}
+		conn, err := dialRaw(procReq.url, args, nil)
+		if err == nil {
+			_, err = conn.Write(payloads[i][:len(payloads[i])-1])
+		}
+		if err != nil {
+			results[i].Err = err
+			if conn != nil {
+				conn.Close()
+			}
+			continue
+		}
+		conns[i] = conn
+	}
+
+	// release every request as close together as possible
+	start := time.Now()
+	for i, conn := range conns {
+		if conn != nil {
+			_, results[i].Err = conn.Write(payloads[i][len(payloads[i])-1:])
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		if conn == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if results[i].Err != nil {
+				return
+			}
+			results[i].Resp, results[i].Arrival, results[i].Err = readRawResponse(conn, procReqs[i].method, start)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// http2Stream is the response to a request sent in an http2 race
+type http2Stream struct {
+	index   int
+	code    int
+	headers []string
+	body    bytes.Buffer
+	arrival time.Duration
+	done    bool
+	err     error
+}
+
+// raceHttp2 sends every request as a stream over a single http2 connection. The final frame of each stream is held
+// back and all of them are written in a single packet
+func raceHttp2(procReqs []*ReqTemplate, args *config.Args) []RaceResult {
+	results := make([]RaceResult, len(procReqs))
+	setErr := func(err error) []RaceResult {
+		for i := range results {
+			if results[i].Resp == nil && results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+		return results
+	}
+
+	conn, err := dialRaw(procReqs[0].url, args, []string{"h2"})
+	if err != nil {
+		return setErr(err)
+	}
+	defer conn.Close()
+	if tlsConn, ok := conn.(*tls.Conn); ok && tlsConn.ConnectionState().NegotiatedProtocol != "h2" {
+		return setErr(errors.New("the server doesn't support http2"))
+	}
+
+	// frames are written to a buffer first so they can be flushed together
+	var out bytes.Buffer
+	framer := http2.NewFramer(&out, conn)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	flush := func() error {
+		_, err := conn.Write(out.Bytes())
+		out.Reset()
+		return err
+	}
+	out.WriteString(http2.ClientPreface)
+	framer.WriteSettings()
+	err = flush()
+	if err != nil {
+		return setErr(err)
+	}
+
+	// send the start of every stream
+	var headerBuf bytes.Buffer
+	encoder := hpack.NewEncoder(&headerBuf)
+	streams := map[uint32]*http2Stream{}
+	final := map[uint32][]byte{}
+	for i, procReq := range procReqs {
+		streamId := uint32(2*i + 1)
+		httpReq := buildHttpRequest(procReq, args)
+		headerBuf.Reset()
+		writeHttp2Headers(encoder, httpReq, procReq.body)
+		framer.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      streamId,
+			BlockFragment: headerBuf.Bytes(),
+			EndHeaders:    true,
+		})
+		final[streamId] = []byte{}
+		if len(procReq.body) > 0 {
+			framer.WriteData(streamId, false, []byte(procReq.body[:len(procReq.body)-1]))
+			final[streamId] = []byte(procReq.body[len(procReq.body)-1:])
+		}
+		streams[streamId] = &http2Stream{index: i}
+	}
+	err = flush()
+	if err != nil {
+		return setErr(err)
+	}
+
+	// release every stream in one packet
+	for i := range procReqs {
+		streamId := uint32(2*i + 1)
+		framer.WriteData(streamId, true, final[streamId])
+	}
+	start := time.Now()
+	err = flush()
+	if err != nil {
+		return setErr(err)
+	}
+
+	remaining := len(streams)
+	for remaining > 0 {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return http2Results(streams, results, err)
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				framer.WritePing(true, f.Data)
+			}
+		case *http2.GoAwayFrame:
+			return http2Results(streams, results, fmt.Errorf("server closed the connection: %s", f.ErrCode))
+		case *http2.MetaHeadersFrame:
+			s, ok := streams[f.StreamID]
+			if !ok {
+				break
+			}
+			if s.code == 0 {
+				s.arrival = time.Since(start)
+				s.code, _ = strconv.Atoi(f.PseudoValue("status"))
+			}
+			for _, field := range f.RegularFields() {
+				s.headers = append(s.headers, field.Name+": "+field.Value)
+			}
+			if f.StreamEnded() && !s.done {
+				s.done = true
+				remaining--
+			}
+		case *http2.DataFrame:
+			s, ok := streams[f.StreamID]
+			if !ok {
+				break
+			}
+			s.body.Write(f.Data())
+			// give the flow control window back so large responses don't stall
+			if len(f.Data()) > 0 {
+				framer.WriteWindowUpdate(0, uint32(len(f.Data())))
+				framer.WriteWindowUpdate(f.StreamID, uint32(len(f.Data())))
+			}
+			if f.StreamEnded() && !s.done {
+				s.done = true
+				remaining--
+			}
+		case *http2.RSTStreamFrame:
+			s, ok := streams[f.StreamID]
+			if ok && !s.done {
+				s.err = fmt.Errorf("stream reset by the server: %s", f.ErrCode)
+				s.done = true
+				remaining--
+			}
+		}
+		if out.Len() > 0 {
+			err = flush()
+			if err != nil {
+				return http2Results(streams, results, err)
+			}
+		}
+	}
+	return http2Results(streams, results, nil)
+}
+
+// writeHttp2Headers encodes the pseudo-headers and headers of the request
+func writeHttp2Headers(encoder *hpack.Encoder, httpReq *http.Request, body string) {
+	authority := httpReq.Host
+	if authority == "" {
+		authority = httpReq.URL.Host
+	}
+	encoder.WriteField(hpack.HeaderField{Name: ":method", Value: httpReq.Method})
+	encoder.WriteField(hpack.HeaderField{Name: ":scheme", Value: httpReq.URL.Scheme})
+	encoder.WriteField(hpack.HeaderField{Name: ":authority", Value: authority})
+	encoder.WriteField(hpack.HeaderField{Name: ":path", Value: requestPath(httpReq.URL)})
+	hasLength := false
+	for name, values := range httpReq.Header {
+		name = strings.ToLower(name)
+		skip := false
+		for _, skipHeader := range http2SkipHeaders {
+			skip = skip || name == skipHeader
+		}
+		if skip {
+			continue
+		}
+		hasLength = hasLength || name == "content-length"
+		for _, value := range values {
+			encoder.WriteField(hpack.HeaderField{Name: name, Value: value})
+		}
+	}
+	if !hasLength && len(body) > 0 {
+		encoder.WriteField(hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(len(body))})
+	}
+}
+
+// requestPath returns the path sent in the request, pseudo-header paths are kept as is
+func requestPath(u *url.URL) string {
+	if u.Opaque != "" {
+		if u.RawQuery != "" {
+			return u.Opaque + "?" + u.RawQuery
+		}
+		return u.Opaque
+	}
+	return u.RequestURI()
+}
+
+// http2Results converts the streams into race results, streams that didn't finish get the error
+func http2Results(streams map[uint32]*http2Stream, results []RaceResult, err error) []RaceResult {
+	for _, s := range streams {
+		r := &results[s.index]
+		r.Arrival = s.arrival
+		switch {
+		case s.err != nil:
+			r.Err = s.err
+		case !s.done && err != nil:
+			r.Err = err
+		case s.code != 0:
+			r.Resp = response.NewResp(s.code, s.headers, s.body.String(), int(s.arrival/time.Millisecond), "HTTP/2.0")
+		}
+	}
+	return results
+}
@@ -0,0 +1,137 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Sceptre-Cybersec/gohammer/config"
+	"github.com/Sceptre-Cybersec/gohammer/processors/request/transforms"
+	"github.com/Sceptre-Cybersec/gohammer/processors/response"
+	"github.com/Sceptre-Cybersec/gohammer/utils"
+)
+
+// FileToRawRequestAgent creates an agent that sends the request file exactly as it was written, without
+// normalising the headers, line endings or content length. The url is only used to decide where to connect
+// and whether to use tls
+func FileToRawRequestAgent(reqContent string, urlBase string, useHttp bool, respectEscapeChars bool) *ReqAgentHttp {
+	method, reqUrl := parseRequestLine(reqContent, urlBase, useHttp)
+	if respectEscapeChars {
+		reqContent = utils.ApplyEscapeCharacters(reqContent)
+		reqUrl = utils.ApplyEscapeCharacters(reqUrl)
+		method = utils.ApplyEscapeCharacters(method)
+	}
+	return &ReqAgentHttp{
+		template:      NewReqTemplate(reqUrl, method, []string{}, reqContent),
+		transformList: transforms.NewTransformList(),
+		raw:           true,
+	}
+}
+
+// rawAddress gets the address to connect to from the request url and whether the connection uses tls
+func rawAddress(reqUrl string) (string, bool, error) {
+	parsedUrl, err := url.Parse(reqUrl)
+	if err != nil {
+		return "", false, err
+	}
+	useTls := parsedUrl.Scheme == "https"
+	if !useTls && parsedUrl.Scheme != "http" {
+		return "", false, fmt.Errorf("unsupported scheme %s in %s", parsedUrl.Scheme, reqUrl)
+	}
+	port := parsedUrl.Port()
+	if port == "" {
+		port = "80"
+		if useTls {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(parsedUrl.Hostname(), port), useTls, nil
+}
+
+// CheckRawScheme checks that the request can be sent over a plain connection, which only supports http and https
+func (req *ReqAgentHttp) CheckRawScheme() error {
+	parsedUrl, err := url.Parse(req.template.url)
+	if err != nil {
+		return err
+	}
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %s in %s, only http and https are supported", parsedUrl.Scheme, req.template.url)
+	}
+	return nil
+}
+
+// sendRaw writes the processed request to a new tcp or tls connection and reads a single response back
+func sendRaw(procReq *ReqTemplate, args *config.Args) (*response.Resp, error) {
+	conn, err := dialRaw(procReq.url, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	_, err = conn.Write([]byte(procReq.body))
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := readRawResponse(conn, procReq.method, start)
+	return r, err
+}
+
+// dialRaw opens a tcp or tls connection to the host of the request url, offering nextProtos during the tls handshake
+func dialRaw(reqUrl string, args *config.Args, nextProtos []string) (net.Conn, error) {
+	addr, useTls, err := rawAddress(reqUrl)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(args.RequestOptions.Timeout)
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if useTls {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			InsecureSkipVerify: true,
+			Renegotiation:      tls.RenegotiateOnceAsClient,
+			ServerName:         host,
+			NextProtos:         nextProtos,
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	return conn, nil
+}
+
+// readRawResponse reads a single response from the connection. Responses that can't be parsed as http are still
+// returned with whatever could be read. Also returns how long it took for the response to arrive after start
+func readRawResponse(conn net.Conn, method string, start time.Time) (*response.Resp, time.Duration, error) {
+	// keep a copy of everything that was read in case the response isn't valid http
+	var rawResp bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(conn, &rawResp))
+	resp, err := http.ReadResponse(reader, &http.Request{Method: method})
+	elapsed := time.Since(start)
+	if err == nil {
+		r := response.NewRespFromHttp(resp, int(elapsed/time.Millisecond), nil)
+		resp.Body.Close()
+		return r, elapsed, nil
+	}
+	io.ReadAll(reader)
+	if rawResp.Len() <= 0 {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("connection closed without a response")
+		}
+		return nil, elapsed, err
+	}
+	return response.NewRespFromTcp(rawResp.String(), int(elapsed/time.Millisecond)), elapsed, nil
+}
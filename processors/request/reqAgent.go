@@ -2,13 +2,14 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"regexp"
 	"strconv"
 
 	// "crypto/tls"
 	"fmt"
-	// "net"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -23,15 +24,19 @@ import (
 )
 
 type ReqTemplate struct {
-	url     string
-	method  string
-	headers []string
-	body    string
+	url        string
+	method     string
+	headers    []string
+	body       string
+	transforms []string
 }
 type ReqAgentHttp struct {
 	template      *ReqTemplate
 	transformList transforms.TransformList
 	client        *http.Client
+	http2Client   *http.Client
+	proxies       *ProxyPool
+	raw           bool
 }
 
 func NewReqTemplate(reqUrl string, method string, headers []string, body string) *ReqTemplate {
@@ -56,6 +61,22 @@ func NewReqAgentHttp(reqUrl string, method string, headers []string, body string
 		method = utils.ApplyEscapeCharacters(method)
 	}
 	template := NewReqTemplate(reqUrl, method, headers, body)
+	agent := &ReqAgentHttp{
+		template:      template,
+		transformList: transforms.NewTransformList(),
+	}
+	if proxy != "" {
+		agent.proxies = getProxyPool(proxy, timeout)
+	} else {
+		agent.client = newHttpClient(timeout, nil, false)
+		agent.http2Client = newHttpClient(timeout, nil, true)
+	}
+	return agent
+}
+
+// newHttpClient creates the client used to send requests, optionally through a proxy.
+// The http2 client only speaks http2, h2 is negotiated over tls and cleartext connections use h2c with prior knowledge
+func newHttpClient(timeout int, proxyUrl *url.URL, http2 bool) *http.Client {
 	client := &http.Client{
 		Timeout:       time.Duration(timeout * int(time.Second)),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
@@ -72,24 +93,26 @@ func NewReqAgentHttp(reqUrl string, method string, headers []string, body string
 			ServerName:         "",
 		},
 	}
-	// add http proxy if exists
-	if proxy != "" {
-		proxyUrl, err := url.Parse(proxy)
-		if err != nil {
-			fmt.Printf("Error: invalid proxy url %s", proxy)
-			os.Exit(1)
+	// add proxy if exists, net/http handles http and socks5 proxies along with their credentials
+	if proxyUrl != nil {
+		if strings.HasPrefix(proxyUrl.Scheme, "socks4") {
+			transportConfig.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				return dialSocks4(ctx, proxyUrl, addr)
+			}
+		} else {
+			transportConfig.Proxy = http.ProxyURL(proxyUrl)
 		}
-		transportConfig.Proxy = http.ProxyURL(proxyUrl)
 	}
 
-	client.Transport = transportConfig
-
-	transformList := transforms.NewTransformList()
-	return &ReqAgentHttp{
-		template:      template,
-		client:        client,
-		transformList: transformList,
+	if http2 {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		transportConfig.Protocols = protocols
 	}
+
+	client.Transport = transportConfig
+	return client
 }
 
 func (req *ReqAgentHttp) GetUrl() string {
@@ -123,10 +146,97 @@ func (req *ReqAgentHttp) HasTransform() bool {
 	return found
 }
 
-func (req *ReqAgentHttp) Send(positions []string, counter *utils.Counter, args *config.Args, previousResponses *[]response.Resp) (bool, error) {
+// Send sends the request and processes the response. Cookies are kept in the jar when it isn't nil
+func (req *ReqAgentHttp) Send(positions []string, counter *utils.Counter, args *config.Args, previousResponses *[]response.Resp, jar http.CookieJar) (bool, error) {
+	proxy := req.nextProxy(args)
+	r, err := req.do(positions, args, previousResponses, proxy, jar)
+	if r == nil {
+		return false, err
+	}
+
+	*previousResponses = append(*previousResponses, *r)
+
+	if args.RequestOptions.Limiter != nil {
+		args.RequestOptions.Limiter.Observe(r.Code, r.HeaderValue("Retry-After"), r.Time)
+	}
+
+	ret, err := r.ProcessResp(positions, counter, args)
+
+	// responses that matched the error filters or requeued a trigger count against the proxy
+	if proxy != nil && err == nil {
+		if ret {
+			req.proxies.Success(proxy)
+		} else {
+			req.proxies.Failure(proxy)
+		}
+	}
+
+	return ret, err
+}
+
+// Do applies the positions to the request template and sends the request without processing the response
+// Returns nil if no response was received
+func (req *ReqAgentHttp) Do(positions []string, args *config.Args, previousResponses *[]response.Resp) (*response.Resp, error) {
+	return req.do(positions, args, previousResponses, req.nextProxy(args), nil)
+}
+
+// nextProxy picks the proxy for the next request, returns nil when no proxy is used
+func (req *ReqAgentHttp) nextProxy(args *config.Args) *UpstreamProxy {
+	if req.proxies == nil {
+		return nil
+	}
+	return req.proxies.Next(args.RequestOptions.ProxyRotate)
+}
+
+func (req *ReqAgentHttp) do(positions []string, args *config.Args, previousResponses *[]response.Resp, proxy *UpstreamProxy, jar http.CookieJar) (*response.Resp, error) {
 
 	// apply positions from wordlist to request template
 	procReq := procReqTemplate(req, positions, args, previousResponses)
+	if req.raw {
+		r, err := sendRaw(procReq, args)
+		if r == nil {
+			return nil, err
+		}
+		r.Request = newReqInfo(procReq, previousResponses)
+		r.Request.Raw = true
+		return r, nil
+	}
+	reqTemplate := buildHttpRequest(procReq, args)
+	client := req.getClient(args, proxy)
+	if jar != nil {
+		// the clients are shared between workers, so each jar gets its own copy
+		jarClient := *client
+		jarClient.Jar = jar
+		client = &jarClient
+	}
+	start := time.Now()
+	resp, err := client.Do(reqTemplate)
+	elapsed := int(time.Since(start) / time.Millisecond)
+	if elapsed > args.RequestOptions.Timeout {
+		fmt.Printf("Elapsed: %d    \tTimeout:%d\n", elapsed, args.RequestOptions.Timeout)
+	}
+	if resp == nil {
+		if proxy != nil {
+			req.proxies.Failure(proxy)
+		}
+		return nil, err
+	}
+
+	r := response.NewRespFromHttp(resp, elapsed, err)
+
+	// an error created by 301 without Location header
+	if r.Code == 0 && err != nil {
+		return nil, err
+	}
+	r.Request = newReqInfo(procReq, previousResponses)
+	if proxy != nil {
+		r.Request.Proxy = proxy.Url.Redacted()
+	}
+	return r, nil
+}
+
+// buildHttpRequest converts the processed request template into a request for the http client
+func buildHttpRequest(procReq *ReqTemplate, args *config.Args) *http.Request {
 	reqTemplate, err := http.NewRequest(procReq.method, procReq.url, bytes.NewBuffer([]byte(procReq.body)))
 
 	if err != nil {
@@ -138,8 +248,18 @@ func (req *ReqAgentHttp) Send(positions []string, counter *utils.Counter, args *
 	for _, header := range headers {
 		splitHeaders := strings.SplitN(header, ": ", 2)
 		if len(splitHeaders) >= 2 {
-			if strings.EqualFold(splitHeaders[0], "Host") {
+			if strings.EqualFold(splitHeaders[0], "Host") || splitHeaders[0] == ":authority" {
 				reqTemplate.Host = splitHeaders[1]
+			} else if splitHeaders[0] == ":path" {
+				// sent as is, without cleaning up the path. The query is kept apart or go would add it a second time
+				path, query, _ := strings.Cut(splitHeaders[1], "?")
+				reqTemplate.URL.Opaque = path
+				reqTemplate.URL.RawQuery = query
+			} else if splitHeaders[0] == ":method" {
+				reqTemplate.Method = splitHeaders[1]
+			} else if strings.HasPrefix(splitHeaders[0], ":") {
+				// the remaining pseudo-headers like :scheme come from the url
+				continue
 			} else if strings.EqualFold(splitHeaders[0], "Content-Length") && !args.RequestOptions.NoUpdateCL {
 				// adjust content length
 				reqTemplate.Header.Set(splitHeaders[0], strconv.Itoa(len(procReq.body)))
@@ -152,28 +272,33 @@ func (req *ReqAgentHttp) Send(positions []string, counter *utils.Counter, args *
 	if encoding == "" {
 		reqTemplate.Header.Set("Accept-Encoding", "*")
 	}
-	start := time.Now()
-	resp, err := req.client.Do(reqTemplate)
-	elapsed := int(time.Since(start) / time.Millisecond)
-	if elapsed > args.RequestOptions.Timeout {
-		fmt.Printf("Elapsed: %d    \tTimeout:%d\n", elapsed, args.RequestOptions.Timeout)
+	return reqTemplate
+}
+
+// getClient returns the client for the http version and proxy of the request
+func (req *ReqAgentHttp) getClient(args *config.Args, proxy *UpstreamProxy) *http.Client {
+	if proxy != nil {
+		if args.RequestOptions.Http2 {
+			return proxy.http2Client
+		}
+		return proxy.client
 	}
-	if resp == nil {
-		return false, err
+	if args.RequestOptions.Http2 {
+		return req.http2Client
 	}
+	return req.client
+}
 
-	r := response.NewRespFromHttp(resp, elapsed, err)
-
-	// an error created by 301 without Location header
-	if r.Code == 0 && err != nil {
-		return false, err
+// newReqInfo describes the processed request for the response
+func newReqInfo(procReq *ReqTemplate, previousResponses *[]response.Resp) response.ReqInfo {
+	return response.ReqInfo{
+		Url:        procReq.url,
+		Method:     procReq.method,
+		Headers:    procReq.headers,
+		Body:       procReq.body,
+		Transforms: procReq.transforms,
+		Step:       len(*previousResponses),
 	}
-
-	*previousResponses = append(*previousResponses, *r)
-
-	ret, err := r.ProcessResp(positions, counter, args)
-
-	return ret, err
 }
 
 // ProcReqTemplate applies words from a set of wordlists to a request template
@@ -194,6 +319,15 @@ func procReqTemplate(reqAgent *ReqAgentHttp, positions []string, args *config.Ar
 		headers = append(headers, utils.ReplacePosition(header, positions, args.RecursionOptions.RecursePosition, args.OutputOptions.Logger))
 	}
 	body := utils.ReplacePosition(reqAgent.GetBody(), positions, args.RecursionOptions.RecursePosition, args.OutputOptions.Logger)
+	// apply the variables extracted from the earlier steps of the chain
+	if len(args.ChainOptions.Extract) > 0 {
+		url = response.ReplaceVariables(url, *previousResponses)
+		method = response.ReplaceVariables(method, *previousResponses)
+		for i := range headers {
+			headers[i] = response.ReplaceVariables(headers[i], *previousResponses)
+		}
+		body = response.ReplaceVariables(body, *previousResponses)
+	}
 	if len(args.TransformOptions.Transforms) > 0 && reqAgent.HasTransform() {
 		// apply transforms too
 		var transformPostions []string
@@ -209,6 +343,9 @@ func procReqTemplate(reqAgent *ReqAgentHttp, positions []string, args *config.Ar
 		}
 		headers = transformedHeaders
 		body = transforms.ReplaceTranformPosition(body, transformPostions, args.OutputOptions.Logger)
+		procReq := NewReqTemplate(url, method, headers, body)
+		procReq.transforms = transformPostions
+		return procReq
 	}
 	return NewReqTemplate(url, method, headers, body)
 }
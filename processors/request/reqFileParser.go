@@ -4,35 +4,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
 )
 
 func FileToRequestAgent(reqContent string, urlBase string, useHttp bool, proxy string, timeout int, removeHeaders []string, respectEscapeChars bool) *ReqAgentHttp {
-	getMethod := regexp.MustCompile(`\S+`)
-	method := getMethod.FindString(reqContent)
-
-	getURL := regexp.MustCompile(`\S+\s(\S+)`)
-	pathGroups := getURL.FindStringSubmatch(reqContent)
-	if len(pathGroups) <= 1 {
-		fmt.Println("Error parsing url path from request file")
-		os.Exit(1)
-	}
-	if urlBase == "" {
-		getHostName := regexp.MustCompile(`(?:h|H)ost:\s(\S+)`)
-		hostGroups := getHostName.FindStringSubmatch(reqContent)
-		if len(hostGroups) < 2 {
-			fmt.Println("Error parsing host from request file")
-			os.Exit(1)
-		}
-		if useHttp {
-			urlBase = "http://"
-		} else {
-			urlBase = "https://"
-		}
-
-		urlBase += hostGroups[1]
-	}
-	path := urlBase + pathGroups[1]
+	method, path := parseRequestLine(reqContent, urlBase, useHttp)
 
 	getContent := regexp.MustCompile(`(?s)(?:\r\n\r\n|\n\n)(.*)`)
 	bodyGroups := getContent.FindStringSubmatch(reqContent)
@@ -48,27 +23,53 @@ func FileToRequestAgent(reqContent string, urlBase string, useHttp bool, proxy s
 	// remove the body content
 	parsedReqFile := getContent.ReplaceAllString(reqContent, "")
 
-	getHeaders := regexp.MustCompile(`[\\\w-]+:\s.*`)
+	// pseudo-headers like :authority come from requests captured over http2
+	getHeaders := regexp.MustCompile(`:?[\\\w-]+:\s.*`)
 	headersArr := getHeaders.FindAllString(parsedReqFile, -1)
 	// remove newlines from headers
-	var cleanedHeaders []string
+	var strippedHeaders []string
 	stripNL := regexp.MustCompile(`\r|\n`)
 	for _, header := range headersArr {
-		//skip headers if they're in the removal list
-		cleanHeader := stripNL.ReplaceAllString(header, "")
-		skip := false
-		for _, removeHeader := range removeHeaders {
-			if strings.HasPrefix(cleanHeader, removeHeader) {
-				skip = true
-				break
-			}
-		}
-		if !skip {
-			cleanedHeaders = append(cleanedHeaders, cleanHeader)
-		}
+		strippedHeaders = append(strippedHeaders, stripNL.ReplaceAllString(header, ""))
 	}
+	//skip headers if they're in the removal list
+	cleanedHeaders := filterHeaders(strippedHeaders, removeHeaders)
 	req := NewReqAgentHttp(path, method, cleanedHeaders, body, proxy, timeout, respectEscapeChars)
 
 	return req
 
 }
+
+// parseRequestLine gets the method and the full url from a request file. The url base is taken from the Host
+// header if it isn't supplied
+func parseRequestLine(reqContent string, urlBase string, useHttp bool) (string, string) {
+	getMethod := regexp.MustCompile(`\S+`)
+	method := getMethod.FindString(reqContent)
+
+	getURL := regexp.MustCompile(`\S+\s(\S+)`)
+	pathGroups := getURL.FindStringSubmatch(reqContent)
+	if len(pathGroups) <= 1 {
+		fmt.Println("Error parsing url path from request file")
+		os.Exit(1)
+	}
+	if urlBase == "" {
+		getHostName := regexp.MustCompile(`(?:h|H)ost:\s(\S+)`)
+		hostGroups := getHostName.FindStringSubmatch(reqContent)
+		if len(hostGroups) < 2 {
+			getAuthority := regexp.MustCompile(`(?m)^:authority:\s(\S+)`)
+			hostGroups = getAuthority.FindStringSubmatch(reqContent)
+		}
+		if len(hostGroups) < 2 {
+			fmt.Println("Error parsing host from request file")
+			os.Exit(1)
+		}
+		if useHttp {
+			urlBase = "http://"
+		} else {
+			urlBase = "https://"
+		}
+
+		urlBase += hostGroups[1]
+	}
+	return method, urlBase + pathGroups[1]
+}
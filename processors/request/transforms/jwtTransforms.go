@@ -0,0 +1,226 @@
+package transforms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+)
+
+// the algorithms jwts can be signed with
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// jwt is a token split into its decoded header and claims, the signature is kept encoded
+type jwt struct {
+	header    map[string]any
+	claims    map[string]any
+	signature string
+}
+
+func jwtDecodeSegment(segment string) (map[string]any, error) {
+	raw, err := b64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return nil, err
+	}
+	decoded := map[string]any{}
+	// keep numbers as they were written so large ids don't lose precision
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err = decoder.Decode(&decoded)
+	return decoded, err
+}
+
+func jwtEncodeSegment(segment map[string]any) string {
+	raw, _ := json.Marshal(segment)
+	return b64.RawURLEncoding.EncodeToString(raw)
+}
+
+func parseJwt(token string) (*jwt, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid jwt " + token)
+	}
+	header, err := jwtDecodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt header (%s)", err.Error())
+	}
+	claims, err := jwtDecodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt claims (%s)", err.Error())
+	}
+	return &jwt{header: header, claims: claims, signature: parts[2]}, nil
+}
+
+func (t *jwt) unsigned() string {
+	return jwtEncodeSegment(t.header) + "." + jwtEncodeSegment(t.claims)
+}
+
+func (t *jwt) String() string {
+	return t.unsigned() + "." + t.signature
+}
+
+// sign sets the algorithm in the header and signs the token with the key
+func (t *jwt) sign(alg string, key []byte) (string, error) {
+	alg = strings.ToUpper(strings.TrimSpace(alg))
+	newHash, ok := jwtAlgorithms[alg]
+	if !ok {
+		return "", fmt.Errorf("unsupported jwt algorithm %s, expected HS256, HS384 or HS512", alg)
+	}
+	t.header["alg"] = alg
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(t.unsigned()))
+	t.signature = b64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return t.String(), nil
+}
+
+// jwtValue formats a claim as a string, anything that isn't a string is returned as json
+func jwtValue(value any) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
+
+func jwtDecode(context TransformContext) string {
+	input := context.Args
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	segment := token.claims
+	if len(input) >= 2 && strings.TrimSpace(input[1]) == "header" {
+		segment = token.header
+	}
+	raw, _ := json.Marshal(segment)
+	return string(raw)
+}
+
+func jwtGet(context TransformContext) string {
+	input := context.Args
+	if len(input) < 2 {
+		fmt.Println("Error: invalid arguments, need jwtGet(<jwt>,<claim>,[header])")
+		return ""
+	}
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	segment := token.claims
+	if len(input) >= 3 && strings.TrimSpace(input[2]) == "header" {
+		segment = token.header
+	}
+	value, ok := segment[input[1]]
+	if !ok {
+		return ""
+	}
+	return jwtValue(value)
+}
+
+func jwtSet(context TransformContext) string {
+	input := context.Args
+	if len(input) < 3 {
+		fmt.Println("Error: invalid arguments, need jwtSet(<jwt>,<claim>,<value>,[json])")
+		return ""
+	}
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	var value any = input[2]
+	// values are strings unless they should be parsed as json, like numbers, booleans or objects
+	if len(input) >= 4 && strings.TrimSpace(input[3]) == "json" {
+		decoder := json.NewDecoder(strings.NewReader(input[2]))
+		decoder.UseNumber()
+		err = decoder.Decode(&value)
+		if err != nil {
+			fmt.Printf("Error: invalid json value %s (%s)\n", input[2], err.Error())
+			return ""
+		}
+	}
+	token.claims[input[1]] = value
+	return token.String()
+}
+
+func jwtSign(context TransformContext) string {
+	input := context.Args
+	if len(input) < 3 {
+		fmt.Println("Error: invalid arguments, need jwtSign(<jwt>,<HS256|HS384|HS512>,<key>)")
+		return ""
+	}
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	output, err := token.sign(input[1], []byte(input[2]))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	return output
+}
+
+// jwtNone removes the signature and sets the algorithm to none, or a different spelling of it like None or nOnE
+func jwtNone(context TransformContext) string {
+	input := context.Args
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	alg := "none"
+	if len(input) >= 2 && strings.TrimSpace(input[1]) != "" {
+		alg = strings.TrimSpace(input[1])
+	}
+	token.header["alg"] = alg
+	token.signature = ""
+	return token.String()
+}
+
+// jwtConfuse signs the token with hmac using the server's public key as the secret, for servers that verify with
+// whatever algorithm the token asks for. The key can be the pem itself or the path to a file containing it
+func jwtConfuse(context TransformContext) string {
+	input := context.Args
+	if len(input) < 2 {
+		fmt.Println("Error: invalid arguments, need jwtConfuse(<jwt>,<public_key_or_file>,[<HS256|HS384|HS512>])")
+		return ""
+	}
+	token, err := parseJwt(input[0])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	key := []byte(input[1])
+	if !strings.Contains(input[1], "-----BEGIN") {
+		key, err = os.ReadFile(input[1])
+		if err != nil {
+			fmt.Printf("Error: cannot read public key %s (%s)\n", input[1], err.Error())
+			return ""
+		}
+	}
+	alg := "HS256"
+	if len(input) >= 3 && strings.TrimSpace(input[2]) != "" {
+		alg = input[2]
+	}
+	output, err := token.sign(alg, key)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	return output
+}
@@ -22,6 +22,7 @@ func ApplyTransforms(transfromTemplates string, transforms TransformList, positi
 	funcName, args := getFuncAndArgs(transfromTemplates)
 	if funcName == "" {
 		outp := normalize(transfromTemplates)
+		outp = response.ReplaceVariables(outp, *previousResponses)
 		return utils.ReplacePosition(outp, positions, conf.RecursionOptions.RecursePosition, conf.OutputOptions.Logger)
 	} else if funcName != "" && len(args) > 0 {
 		var argList []string
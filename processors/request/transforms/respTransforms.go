@@ -0,0 +1,60 @@
+package transforms
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Sceptre-Cybersec/gohammer/processors/response"
+	"github.com/Sceptre-Cybersec/gohammer/utils"
+)
+
+// unquote removes the quotes around a name so both header(prevResponse(0), Location) and
+// header(prevResponse(0), "Location") work
+func unquote(input string) string {
+	input = strings.TrimSpace(input)
+	if len(input) >= 2 && (input[0] == '"' || input[0] == '\'') && input[len(input)-1] == input[0] {
+		return input[1 : len(input)-1]
+	}
+	return input
+}
+
+// jsonPath runs a json path on the body of a response from prevResponse, or on a plain json string
+func jsonPath(context TransformContext) string {
+	input := context.Args
+	if len(input) < 2 {
+		fmt.Println("Error: invalid arguments, need json(<response_or_json>,<json_path>)")
+		return ""
+	}
+	body := response.NewRespFromString(input[0]).Body
+	output, _, err := utils.JsonPathString(body, input[1])
+	if err != nil {
+		fmt.Printf("Error: cannot run json path %s (%s)\n", input[1], err.Error())
+		return ""
+	}
+	return output
+}
+
+func header(context TransformContext) string {
+	input := context.Args
+	if len(input) < 2 {
+		fmt.Println("Error: invalid arguments, need header(<response>,<header_name>)")
+		return ""
+	}
+	return response.NewRespFromString(input[0]).HeaderValue(unquote(input[1]))
+}
+
+func status(context TransformContext) string {
+	input := context.Args
+	return strconv.Itoa(response.NewRespFromString(input[0]).Code)
+}
+
+func cookie(context TransformContext) string {
+	input := context.Args
+	if len(input) < 2 {
+		fmt.Println("Error: invalid arguments, need cookie(<response>,<cookie_name>)")
+		return ""
+	}
+	value, _ := response.NewRespFromString(input[0]).Cookie(unquote(input[1]))
+	return value
+}
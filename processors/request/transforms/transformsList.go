@@ -1,10 +1,16 @@
 package transforms
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	b64 "encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"math"
 	"math/big"
 	"net/url"
@@ -12,6 +18,8 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type TransformList map[string]func(TransformContext) string
@@ -30,6 +38,22 @@ func NewTransformList() TransformList {
 		"randBytes":    randBytes,
 		"regex":        regex,
 		"prevResponse": prevResponse,
+		"md5":          hashFunc(md5.New),
+		"sha1":         hashFunc(sha1.New),
+		"sha256":       hashFunc(sha256.New),
+		"sha512":       hashFunc(sha512.New),
+		"bcrypt":       bcryptHash,
+		"hmac":         hmacHash,
+		"jwtDecode":    jwtDecode,
+		"jwtGet":       jwtGet,
+		"jwtSet":       jwtSet,
+		"jwtSign":      jwtSign,
+		"jwtNone":      jwtNone,
+		"jwtConfuse":   jwtConfuse,
+		"json":         jsonPath,
+		"header":       header,
+		"status":       status,
+		"cookie":       cookie,
 	}
 	return t
 }
@@ -245,3 +269,85 @@ func concat(context TransformContext) string {
 	}
 	return output
 }
+
+// the hash algorithms that can be used with hmac
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// encodeDigest formats a digest as hex (the default) or base64
+func encodeDigest(digest []byte, format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "hex":
+		return hex.EncodeToString(digest), nil
+	case "b64", "base64":
+		return b64.StdEncoding.EncodeToString(digest), nil
+	}
+	return "", fmt.Errorf("unknown output format %s, expected hex or b64", format)
+}
+
+// hashFunc creates a transform that hashes its first argument, the optional second argument is the output format
+func hashFunc(newHash func() hash.Hash) func(TransformContext) string {
+	return func(context TransformContext) string {
+		input := context.Args
+		h := newHash()
+		h.Write([]byte(input[0]))
+		format := ""
+		if len(input) >= 2 {
+			format = input[1]
+		}
+		output, err := encodeDigest(h.Sum(nil), format)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return ""
+		}
+		return output
+	}
+}
+
+func bcryptHash(context TransformContext) string {
+	input := context.Args
+	cost := int64(bcrypt.DefaultCost)
+	if len(input) >= 2 && strings.TrimSpace(input[1]) != "" {
+		var err error
+		cost, err = strToInt(strings.TrimSpace(input[1]))
+		if err != nil {
+			fmt.Printf("Error: invalid bcrypt cost %s\n", input[1])
+			return ""
+		}
+	}
+	output, err := bcrypt.GenerateFromPassword([]byte(input[0]), int(cost))
+	if err != nil {
+		fmt.Printf("Error: cannot bcrypt hash %s (%s)\n", input[0], err.Error())
+		return ""
+	}
+	return string(output)
+}
+
+func hmacHash(context TransformContext) string {
+	input := context.Args
+	if len(input) < 3 {
+		fmt.Println("Error: invalid arguments, need hmac(<md5|sha1|sha256|sha512>,<key>,<data>,[<hex|b64>])")
+		return ""
+	}
+	newHash, ok := hashAlgorithms[strings.ToLower(strings.TrimSpace(input[0]))]
+	if !ok {
+		fmt.Printf("Error: unknown hmac algorithm %s\n", input[0])
+		return ""
+	}
+	mac := hmac.New(newHash, []byte(input[1]))
+	mac.Write([]byte(input[2]))
+	format := ""
+	if len(input) >= 4 {
+		format = input[3]
+	}
+	output, err := encodeDigest(mac.Sum(nil), format)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return ""
+	}
+	return output
+}
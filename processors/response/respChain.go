@@ -0,0 +1,70 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StepExpr is a filter expression that applies to one step of the request chain, written as step:expr
+type StepExpr struct {
+	Step int
+	Expr *Expr
+}
+
+var stepExprCache sync.Map
+
+// ParseStepExpr parses a filter expression for a step of the request chain, for example 1:code == 302
+func ParseStepExpr(spec string) (*StepExpr, error) {
+	stepStr, src, found := strings.Cut(spec, ":")
+	if !found {
+		return nil, errors.New("expected step:expression")
+	}
+	step, err := strconv.Atoi(strings.TrimSpace(stepStr))
+	if err != nil || step < 0 {
+		return nil, fmt.Errorf("invalid step %s", stepStr)
+	}
+	expr, err := ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	return &StepExpr{Step: step, Expr: expr}, nil
+}
+
+// getStepExpr returns the parsed step expression, they're only parsed once since they're checked for every chain
+func getStepExpr(spec string) *StepExpr {
+	if e, ok := stepExprCache.Load(spec); ok {
+		return e.(*StepExpr)
+	}
+	e, err := ParseStepExpr(spec)
+	if err != nil {
+		fmt.Printf("Error: Invalid step expression (%s)\n", err.Error())
+		os.Exit(1)
+	}
+	stepExprCache.Store(spec, e)
+	return e
+}
+
+// MatchStep evaluates the expressions that apply to the step against the response. All of them need to match unless
+// any is true, in which case one is enough. found is false if none of the expressions apply to the step
+func MatchStep(specs []string, step int, resp *Resp, any bool) (matched bool, found bool) {
+	for _, spec := range specs {
+		e := getStepExpr(spec)
+		if e.Step != step {
+			continue
+		}
+		result := e.Expr.Eval(resp)
+		if !found {
+			matched = result
+		} else if any {
+			matched = matched || result
+		} else {
+			matched = matched && result
+		}
+		found = true
+	}
+	return matched, found
+}
@@ -0,0 +1,120 @@
+package response
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ToRaw formats the response the way it came back from the server, responses that weren't valid http are kept as
+// they were received
+func (r *Resp) ToRaw() string {
+	if r.Code == 0 {
+		return r.Body
+	}
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	res := fmt.Sprintf("%s %d %s\r\n", proto, r.Code, http.StatusText(r.Code))
+	for _, header := range r.Headers {
+		res += header + "\r\n"
+	}
+	return res + "\r\n" + r.Body
+}
+
+// Parts gets the method, url, headers and body of the request. Raw requests are read as http, so anything the http
+// client can't send like a malformed request line is lost. The pseudo-headers of http2 request files are applied to
+// the method, url and Host header
+func (r *ReqInfo) Parts() (string, string, []string, string, error) {
+	if !r.Raw {
+		method, path, host, headers, http2 := r.target()
+		parsedUrl, err := url.Parse(r.Url)
+		if !http2 || err != nil {
+			return r.Method, r.Url, r.Headers, r.Body, nil
+		}
+		if host != parsedUrl.Host && !slices.ContainsFunc(headers, func(header string) bool {
+			return strings.HasPrefix(strings.ToLower(header), "host:")
+		}) {
+			headers = append([]string{"Host: " + host}, headers...)
+		}
+		return method, parsedUrl.Scheme + "://" + parsedUrl.Host + path, headers, r.Body, nil
+	}
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(r.Body)))
+	if err != nil {
+		return "", "", nil, "", fmt.Errorf("the raw request isn't valid http (%s)", err.Error())
+	}
+	body, _ := io.ReadAll(req.Body)
+	reqUrl := r.Url
+	if parsedUrl, err := url.Parse(r.Url); err == nil {
+		reqUrl = parsedUrl.Scheme + "://" + parsedUrl.Host + req.RequestURI
+	}
+	headers := []string{}
+	for name, values := range req.Header {
+		for _, value := range values {
+			headers = append(headers, name+": "+value)
+		}
+	}
+	// http keeps the headers in a map so they're sorted to keep them in the same order every time
+	slices.Sort(headers)
+	return req.Method, reqUrl, headers, string(body), nil
+}
+
+// ToCurl formats the request as a one line curl command
+func (r *ReqInfo) ToCurl() string {
+	method, reqUrl, headers, body, err := r.Parts()
+	if err != nil {
+		return "# " + err.Error() + " so it can't be sent with curl, send the .req file instead"
+	}
+	cmd := "curl -k --path-as-is -X " + shellQuote(method) + " " + shellQuote(reqUrl)
+	for _, header := range headers {
+		// curl works out the length of the body itself
+		if strings.HasPrefix(strings.ToLower(header), "content-length:") {
+			continue
+		}
+		cmd += " -H " + shellQuote(header)
+	}
+	if body != "" {
+		cmd += " --data-raw " + shellQuote(body)
+	}
+	return cmd
+}
+
+// shellQuote quotes a string for bash. Strings with newlines or other special bytes use $'...' so the command stays
+// on one line
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@") == "" {
+		return s
+	}
+	special := false
+	for i := 0; i < len(s); i++ {
+		special = special || s[i] < 0x20 || s[i] >= 0x7f
+	}
+	if !special {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	var quoted strings.Builder
+	quoted.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\n':
+			quoted.WriteString(`\n`)
+		case c == '\r':
+			quoted.WriteString(`\r`)
+		case c == '\t':
+			quoted.WriteString(`\t`)
+		case c == '\\' || c == '\'':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&quoted, `\x%02x`, c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	return quoted.String() + "'"
+}
@@ -0,0 +1,463 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Expr is a compiled filter expression such as:
+//
+//	code in [200,302] && size > 1200 && !body ~ "Invalid" && header["Set-Cookie"] contains "session"
+//
+// Supported fields are code, size, words, lines, time, body, headers (all headers as text) and header["Name"].
+// Supported operators are && || ! == != < <= > >= ~ (regex match) !~ in and contains. Lists can contain
+// numbers, ranges like 200-299, or strings
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+type exprType int
+
+const (
+	exprBool exprType = iota
+	exprInt
+	exprString
+	exprList
+)
+
+var exprTypeNames = map[exprType]string{exprBool: "boolean", exprInt: "number", exprString: "string", exprList: "list"}
+
+type listItem struct {
+	low  int
+	high int
+	str  string
+}
+
+type exprNode struct {
+	kind    exprType
+	eval    func(*Resp) any
+	literal bool
+	list    []listItem
+}
+
+type exprToken struct {
+	kind  string // one of num, str, ident, op, eof
+	value string
+	pos   int
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+var exprCache sync.Map
+
+// ParseExpr compiles a filter expression
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().value, p.peek().pos)
+	}
+	root, err = asBool(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// getExpr returns the compiled expression from the cache, compiling it if it hasn't been seen before
+func getExpr(src string) (*Expr, error) {
+	if e, ok := exprCache.Load(src); ok {
+		return e.(*Expr), nil
+	}
+	e, err := ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	exprCache.Store(src, e)
+	return e, nil
+}
+
+// Eval returns true if the response matches the expression
+func (e *Expr) Eval(resp *Resp) bool {
+	return e.root.eval(resp).(bool)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	runes := []rune(src)
+	twoCharOps := []string{"&&", "||", "==", "!=", "<=", ">=", "!~"}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "num", value: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", value: string(runes[start:i]), pos: start})
+		case r == '"':
+			// only \" and \\ are escapes so regular expressions don't need to be double escaped
+			start := i
+			var str strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				str.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: "str", value: str.String(), pos: start})
+		default:
+			op := string(r)
+			if i+1 < len(runes) && contains(twoCharOps, string(runes[i:i+2])) {
+				op = string(runes[i : i+2])
+			} else if !strings.ContainsRune("!<>~()[],-", r) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, exprToken{kind: "op", value: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	tokens = append(tokens, exprToken{kind: "eof", value: "end of expression", pos: len(runes)})
+	return tokens, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(kind string, values ...string) bool {
+	t := p.peek()
+	if t.kind == kind && (len(values) == 0 || contains(values, t.value)) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(value string) error {
+	t := p.next()
+	if t.value != value || t.kind == "str" {
+		return fmt.Errorf("expected %q at position %d but found %q", value, t.pos, t.value)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return left, err
+	}
+	for p.accept("op", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return right, err
+		}
+		l, r, err := asBoolPair(left, right)
+		if err != nil {
+			return l, err
+		}
+		left = exprNode{kind: exprBool, eval: func(resp *Resp) any { return l.eval(resp).(bool) || r.eval(resp).(bool) }}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return left, err
+	}
+	for p.accept("op", "&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return right, err
+		}
+		l, r, err := asBoolPair(left, right)
+		if err != nil {
+			return l, err
+		}
+		left = exprNode{kind: exprBool, eval: func(resp *Resp) any { return l.eval(resp).(bool) && r.eval(resp).(bool) }}
+	}
+	return left, nil
+}
+
+// parseNot handles negation, it binds looser than comparisons so !body ~ "x" negates the whole match
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.accept("op", "!") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return inner, err
+		}
+		inner, err = asBool(inner)
+		if err != nil {
+			return inner, err
+		}
+		return exprNode{kind: exprBool, eval: func(resp *Resp) any { return !inner.eval(resp).(bool) }}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return left, err
+	}
+	opToken := p.peek()
+	isOp := opToken.kind == "op" && contains([]string{"==", "!=", "<", "<=", ">", ">=", "~", "!~"}, opToken.value)
+	isWord := opToken.kind == "ident" && (opToken.value == "in" || opToken.value == "contains")
+	if !isOp && !isWord {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return right, err
+	}
+	return compare(opToken, left, right)
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case "num":
+		i, err := strconv.Atoi(t.value)
+		if err != nil {
+			return exprNode{}, fmt.Errorf("invalid number %s at position %d", t.value, t.pos)
+		}
+		return exprNode{kind: exprInt, literal: true, eval: func(*Resp) any { return i }}, nil
+	case "str":
+		s := t.value
+		return exprNode{kind: exprString, literal: true, eval: func(*Resp) any { return s }}, nil
+	case "ident":
+		return p.parseField(t)
+	case "op":
+		switch t.value {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return inner, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+	return exprNode{}, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+}
+
+func (p *exprParser) parseField(t exprToken) (exprNode, error) {
+	intField := func(get func(*Resp) int) (exprNode, error) {
+		return exprNode{kind: exprInt, eval: func(resp *Resp) any { return get(resp) }}, nil
+	}
+	switch strings.ToLower(t.value) {
+	case "code":
+		return intField(func(r *Resp) int { return r.Code })
+	case "size":
+		return intField(func(r *Resp) int { return r.Size })
+	case "words":
+		return intField(func(r *Resp) int { return r.Words })
+	case "lines":
+		return intField(func(r *Resp) int { return r.Lines })
+	case "time":
+		return intField(func(r *Resp) int { return r.Time })
+	case "true", "false":
+		b := strings.ToLower(t.value) == "true"
+		return exprNode{kind: exprBool, literal: true, eval: func(*Resp) any { return b }}, nil
+	case "body":
+		return exprNode{kind: exprString, eval: func(r *Resp) any { return r.Body }}, nil
+	case "headers":
+		return exprNode{kind: exprString, eval: func(r *Resp) any { return strings.Join(r.Headers, "\n") }}, nil
+	case "header":
+		err := p.expect("[")
+		if err != nil {
+			return exprNode{}, err
+		}
+		name := p.next()
+		if name.kind != "str" {
+			return exprNode{}, fmt.Errorf("expected a header name string at position %d", name.pos)
+		}
+		err = p.expect("]")
+		if err != nil {
+			return exprNode{}, err
+		}
+		return exprNode{kind: exprString, eval: func(r *Resp) any { return r.HeaderValue(name.value) }}, nil
+	}
+	return exprNode{}, fmt.Errorf("unknown field %s at position %d", t.value, t.pos)
+}
+
+func (p *exprParser) parseList() (exprNode, error) {
+	list := []listItem{}
+	for !p.accept("op", "]") {
+		if len(list) > 0 {
+			err := p.expect(",")
+			if err != nil {
+				return exprNode{}, err
+			}
+		}
+		t := p.next()
+		switch t.kind {
+		case "str":
+			list = append(list, listItem{str: t.value})
+		case "num":
+			low, _ := strconv.Atoi(t.value)
+			high := low
+			if p.accept("op", "-") {
+				highToken := p.next()
+				if highToken.kind != "num" {
+					return exprNode{}, fmt.Errorf("expected a number at position %d", highToken.pos)
+				}
+				high, _ = strconv.Atoi(highToken.value)
+			}
+			list = append(list, listItem{low: min(low, high), high: max(low, high)})
+		default:
+			return exprNode{}, fmt.Errorf("expected a number, range or string in list at position %d but found %q", t.pos, t.value)
+		}
+	}
+	return exprNode{kind: exprList, literal: true, list: list, eval: func(*Resp) any { return list }}, nil
+}
+
+// compare builds the node for a binary comparison, checking that the types make sense
+func compare(op exprToken, left exprNode, right exprNode) (exprNode, error) {
+	typeErr := fmt.Errorf("can't use %s with a %s and a %s at position %d", op.value, exprTypeNames[left.kind], exprTypeNames[right.kind], op.pos)
+	boolNode := func(fn func(resp *Resp) bool) (exprNode, error) {
+		return exprNode{kind: exprBool, eval: func(resp *Resp) any { return fn(resp) }}, nil
+	}
+	switch op.value {
+	case "==", "!=":
+		if left.kind != right.kind || left.kind == exprList {
+			return exprNode{}, typeErr
+		}
+		negate := op.value == "!="
+		return boolNode(func(resp *Resp) bool { return (left.eval(resp) == right.eval(resp)) != negate })
+	case "<", "<=", ">", ">=":
+		if left.kind != exprInt || right.kind != exprInt {
+			return exprNode{}, typeErr
+		}
+		return boolNode(func(resp *Resp) bool {
+			l := left.eval(resp).(int)
+			r := right.eval(resp).(int)
+			switch op.value {
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			}
+			return l >= r
+		})
+	case "~", "!~":
+		if left.kind != exprString || right.kind != exprString {
+			return exprNode{}, typeErr
+		}
+		negate := op.value == "!~"
+		if right.literal {
+			re, err := regexp.Compile(right.eval(nil).(string))
+			if err != nil {
+				return exprNode{}, fmt.Errorf("invalid regular expression at position %d: %s", op.pos, err.Error())
+			}
+			return boolNode(func(resp *Resp) bool { return re.MatchString(left.eval(resp).(string)) != negate })
+		}
+		return boolNode(func(resp *Resp) bool {
+			re, err := regexp.Compile(right.eval(resp).(string))
+			return err == nil && re.MatchString(left.eval(resp).(string)) != negate
+		})
+	case "contains":
+		if left.kind != exprString || right.kind != exprString {
+			return exprNode{}, typeErr
+		}
+		return boolNode(func(resp *Resp) bool { return strings.Contains(left.eval(resp).(string), right.eval(resp).(string)) })
+	case "in":
+		if right.kind != exprList || (left.kind != exprInt && left.kind != exprString) {
+			return exprNode{}, typeErr
+		}
+		return boolNode(func(resp *Resp) bool { return inList(left.eval(resp), right.list) })
+	}
+	return exprNode{}, typeErr
+}
+
+func inList(value any, list []listItem) bool {
+	for _, item := range list {
+		switch v := value.(type) {
+		case int:
+			if item.str == "" && v >= item.low && v <= item.high {
+				return true
+			}
+		case string:
+			if item.str == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// asBool converts a node to a boolean, strings are true when they aren't empty so header["X"] on its own
+// checks if the header exists
+func asBool(node exprNode) (exprNode, error) {
+	switch node.kind {
+	case exprBool:
+		return node, nil
+	case exprString:
+		return exprNode{kind: exprBool, eval: func(resp *Resp) any { return node.eval(resp).(string) != "" }}, nil
+	}
+	return node, errors.New("expected a condition but found a " + exprTypeNames[node.kind])
+}
+
+func asBoolPair(left exprNode, right exprNode) (exprNode, exprNode, error) {
+	l, err := asBool(left)
+	if err != nil {
+		return l, right, err
+	}
+	r, err := asBool(right)
+	return l, r, err
+}
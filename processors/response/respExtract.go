@@ -0,0 +1,158 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Sceptre-Cybersec/gohammer/utils"
+)
+
+// the kinds of extractors and what they run on
+var extractorKinds = []string{"regex", "json", "header", "cookie"}
+
+var variableRegex = regexp.MustCompile(`@\{([\w-]+)\}@`)
+var variableNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// Extractor binds a name to a value taken from the response of a step in the request chain
+type Extractor struct {
+	Step  int
+	Name  string
+	Kind  string
+	Expr  string
+	regex *regexp.Regexp
+	path  *utils.JsonPath
+}
+
+var extractorCache sync.Map
+
+// ParseExtractor parses an extractor in the form step:name=kind:expr, for example 0:csrf=regex:name="csrf" value="([^"]+)"
+func ParseExtractor(spec string) (*Extractor, error) {
+	stepStr, rest, found := strings.Cut(spec, ":")
+	if !found {
+		return nil, errors.New("expected step:name=kind:expr")
+	}
+	step, err := strconv.Atoi(strings.TrimSpace(stepStr))
+	if err != nil || step < 0 {
+		return nil, fmt.Errorf("invalid step %s", stepStr)
+	}
+	name, rest, found := strings.Cut(rest, "=")
+	if !found {
+		return nil, errors.New("expected step:name=kind:expr")
+	}
+	name = strings.TrimSpace(name)
+	if !variableNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid variable name '%s', only letters, numbers, _ and - are allowed", name)
+	}
+	kind, expr, found := strings.Cut(rest, ":")
+	if !found || expr == "" {
+		return nil, fmt.Errorf("missing expression for %s", name)
+	}
+	e := Extractor{Step: step, Name: name, Kind: strings.TrimSpace(kind), Expr: expr}
+	switch e.Kind {
+	case "regex":
+		e.regex, err = regexp.Compile(expr)
+	case "json":
+		e.path, err = utils.ParseJsonPath(expr)
+	case "header", "cookie":
+	default:
+		err = fmt.Errorf("unknown extractor %s, expected one of %s", e.Kind, strings.Join(extractorKinds, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// getExtractor returns the parsed extractor, extractors are only parsed once since they run for every chain
+func getExtractor(spec string) *Extractor {
+	if e, ok := extractorCache.Load(spec); ok {
+		return e.(*Extractor)
+	}
+	e, err := ParseExtractor(spec)
+	if err != nil {
+		fmt.Printf("Error: Invalid extractor (%s)\n", err.Error())
+		os.Exit(1)
+	}
+	extractorCache.Store(spec, e)
+	return e
+}
+
+// Extract gets the value from the response. The regex extractor returns the first group, or the whole match if
+// there are no groups
+func (e *Extractor) Extract(resp *Resp) (string, error) {
+	switch e.Kind {
+	case "regex":
+		match := e.regex.FindStringSubmatch(resp.ToString())
+		if match == nil {
+			return "", fmt.Errorf("regex %s didn't match", e.Expr)
+		}
+		if len(match) > 1 {
+			return match[1], nil
+		}
+		return match[0], nil
+	case "json":
+		value, found, err := e.path.FindString(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("json path %s didn't match", e.Expr)
+		}
+		return value, nil
+	case "header":
+		value := resp.HeaderValue(e.Expr)
+		if value == "" {
+			return "", fmt.Errorf("header %s not found", e.Expr)
+		}
+		return value, nil
+	case "cookie":
+		value, found := resp.Cookie(e.Expr)
+		if !found {
+			return "", fmt.Errorf("cookie %s not found", e.Expr)
+		}
+		return value, nil
+	}
+	return "", errors.New("unknown extractor " + e.Kind)
+}
+
+// ExtractVariables runs the extractors for the step on its response and keeps the values on the response so later
+// steps can use them. Returns an error naming the first variable that couldn't be extracted
+func ExtractVariables(resp *Resp, step int, specs []string) error {
+	for _, spec := range specs {
+		e := getExtractor(spec)
+		if e.Step != step {
+			continue
+		}
+		value, err := e.Extract(resp)
+		if err != nil {
+			return fmt.Errorf("couldn't extract %s from the response of step %d (%s)", e.Name, step, err.Error())
+		}
+		if resp.Vars == nil {
+			resp.Vars = map[string]string{}
+		}
+		resp.Vars[e.Name] = value
+	}
+	return nil
+}
+
+// ReplaceVariables replaces @{name}@ with the value extracted by an earlier step, the latest value wins.
+// Variables that haven't been extracted are left as they are
+func ReplaceVariables(str string, previousResponses []Resp) string {
+	if !strings.Contains(str, "@{") {
+		return str
+	}
+	return variableRegex.ReplaceAllStringFunc(str, func(match string) string {
+		name := variableRegex.FindStringSubmatch(match)[1]
+		for i := len(previousResponses) - 1; i >= 0; i-- {
+			if value, ok := previousResponses[i].Vars[name]; ok {
+				return value
+			}
+		}
+		return match
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/Sceptre-Cybersec/gohammer/config"
 )
@@ -16,7 +18,7 @@ type Filter struct {
 func NewFilter(resp *Resp) *Filter {
 	f := Filter{
 		response: resp,
-		filters:  []func(*Resp, *config.FilterOptions) bool{passedCodeFound, passedLengthFilter, passedLengthMatch, passedTimeFilter, passedRegexFilter, passedRegexMatch},
+		filters:  []func(*Resp, *config.FilterOptions) bool{passedCodeFound, passedLengthFilter, passedLengthMatch, passedTimeFilter, passedRegexFilter, passedRegexMatch, passedHeaderFilter, passedHeaderMatch, passedBaselineFilter, passedExprFilter},
 	}
 	return &f
 }
@@ -36,10 +38,11 @@ func (f *Filter) ApplyFilters(args *config.FilterOptions) bool {
 // passedTimeFilter determines if a request fails based on the time it took to reply
 func passedTimeFilter(resp *Resp, args *config.FilterOptions) bool {
 	passed := true
-	if args.Ft != 0 {
-		passed = resp.Time < args.Ft
-	} else if args.Mt != 0 {
-		passed = resp.Time >= args.Mt
+	if len(args.Ft) > 0 {
+		passed = !config.IntRanges(args.Ft).Contains(resp.Time)
+	}
+	if passed && len(args.Mt) > 0 {
+		passed = config.IntRanges(args.Mt).Contains(resp.Time)
 	}
 	return passed
 }
@@ -52,6 +55,10 @@ func passedCodeFound(resp *Resp, args *config.FilterOptions) bool {
 	if len(args.Mc) > 0 && args.Mc[0] == -1 {
 		return true
 	}
+	// a filter expression replaces the default match codes
+	if len(args.Mc) <= 0 && args.Expr != "" {
+		return true
+	}
 	//else scan through accepted response codes
 	for _, i := range args.Mc {
 		if resp.Code == i {
@@ -92,14 +99,130 @@ func passedRegexMatch(resp *Resp, args *config.FilterOptions) bool {
 	return passed
 }
 
+// HeaderFilter matches responses that have a header, optionally with a value matching a regular expression
+type HeaderFilter struct {
+	Name  string
+	Value *regexp.Regexp
+}
+
+var headerFilterCache sync.Map
+
+// ParseHeaderFilter parses a header filter in the form "Name" or "Name: regex"
+func ParseHeaderFilter(spec string) (*HeaderFilter, error) {
+	split := strings.SplitN(spec, ":", 2)
+	h := HeaderFilter{Name: strings.TrimSpace(split[0])}
+	if h.Name == "" {
+		return nil, fmt.Errorf("missing header name in '%s'", spec)
+	}
+	if len(split) == 2 {
+		re, err := regexp.Compile(strings.TrimSpace(split[1]))
+		if err != nil {
+			return nil, err
+		}
+		h.Value = re
+	}
+	return &h, nil
+}
+
+// getHeaderFilter returns the parsed header filter, filters are only parsed once since they're checked for every response
+func getHeaderFilter(spec string) *HeaderFilter {
+	if h, ok := headerFilterCache.Load(spec); ok {
+		return h.(*HeaderFilter)
+	}
+	h, err := ParseHeaderFilter(spec)
+	if err != nil {
+		fmt.Printf("Error: Invalid header filter (%s)\n", err.Error())
+		os.Exit(1)
+	}
+	headerFilterCache.Store(spec, h)
+	return h
+}
+
+// Matches returns true if any header with the filter's name exists, and has a matching value if a regex was given
+func (h *HeaderFilter) Matches(resp *Resp) bool {
+	for _, header := range resp.Headers {
+		split := strings.SplitN(header, ":", 2)
+		if len(split) != 2 || !strings.EqualFold(strings.TrimSpace(split[0]), h.Name) {
+			continue
+		}
+		if h.Value == nil || h.Value.MatchString(strings.TrimSpace(split[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// passedHeaderFilter returns false if any of the header filters or header count filters match the response
+func passedHeaderFilter(resp *Resp, args *config.FilterOptions) bool {
+	for _, spec := range args.Fh {
+		if getHeaderFilter(spec).Matches(resp) {
+			return false
+		}
+	}
+	return !config.IntRanges(args.Fhc).Contains(len(resp.Headers))
+}
+
+// passedHeaderMatch returns true if all of the header matches match the response and the number of headers is in
+// one of the header count ranges
+func passedHeaderMatch(resp *Resp, args *config.FilterOptions) bool {
+	for _, spec := range args.Mh {
+		if !getHeaderFilter(spec).Matches(resp) {
+			return false
+		}
+	}
+	if len(args.Mhc) > 0 {
+		return config.IntRanges(args.Mhc).Contains(len(resp.Headers))
+	}
+	return true
+}
+
+// passedExprFilter returns true if the response matches the filter expression
+func passedExprFilter(resp *Resp, args *config.FilterOptions) bool {
+	if args.Expr == "" {
+		return true
+	}
+	expr, err := getExpr(args.Expr)
+	if err != nil {
+		fmt.Printf("Error: Invalid filter expression (%s)\n", err.Error())
+		os.Exit(1)
+	}
+	return expr.Eval(resp)
+}
+
+// passedBaselineFilter returns false if the response has the same shape as one of the calibrated baselines of its step
+func passedBaselineFilter(resp *Resp, args *config.FilterOptions) bool {
+	for _, b := range args.Calibrated {
+		if b.Step == resp.Request.Step && resp.MatchesBaseline(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesBaseline returns true if the response has the same code as the baseline and the same size, words,
+// lines and number of headers for each part of the baseline that doesn't vary
+func (r *Resp) MatchesBaseline(b config.Baseline) bool {
+	values := []int{r.Size, r.Words, r.Lines, len(r.Headers)}
+	baseline := []int{b.Size, b.Words, b.Lines, b.Headers}
+	if r.Code != b.Code {
+		return false
+	}
+	for i, v := range values {
+		if baseline[i] != -1 && baseline[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
 // passedLengthFilter takes the response sizes (chars, words, lines) respectively as an array and returns true if none of the
 // length filters captures a response length
 func passedLengthFilter(resp *Resp, args *config.FilterOptions) bool {
 	filterPassed := true
-	filters := [][]int{args.Fs, args.Fw, args.Fl}
+	filters := []config.IntRanges{config.IntRanges(args.Fs), config.IntRanges(args.Fw), config.IntRanges(args.Fl)}
 	sizes := []int{resp.Size, resp.Words, resp.Lines}
 	for i, s := range sizes { //apply length filter to chars, words, lines
-		filterPassed = !lenFilterSearch(s, filters[i])
+		filterPassed = !filters[i].Contains(s)
 		if !filterPassed {
 			break
 		}
@@ -112,11 +235,11 @@ func passedLengthFilter(resp *Resp, args *config.FilterOptions) bool {
 // length filters captures a response length
 func passedLengthMatch(resp *Resp, args *config.FilterOptions) bool {
 	filterPassed := true
-	filters := [][]int{args.Ms, args.Mw, args.Ml}
+	filters := []config.IntRanges{config.IntRanges(args.Ms), config.IntRanges(args.Mw), config.IntRanges(args.Ml)}
 	sizes := []int{resp.Size, resp.Words, resp.Lines}
 	for i, s := range sizes { //apply length matcher to chars, words
 		// we only care if the user has specified matchers
-		filterPassed = len(filters[i]) <= 0 || lenFilterSearch(s, filters[i])
+		filterPassed = len(filters[i]) <= 0 || filters[i].Contains(s)
 		if !filterPassed {
 			break
 		}
@@ -124,17 +247,3 @@ func passedLengthMatch(resp *Resp, args *config.FilterOptions) bool {
 
 	return filterPassed
 }
-
-// lenFilterSearch returns true if the length is in the array of lengths
-func lenFilterSearch(length int, lengths []int) bool {
-	ret := false
-	if len(lengths) > 0 {
-		for _, s := range lengths {
-			if s == length {
-				ret = true
-				break
-			}
-		}
-	}
-	return ret
-}
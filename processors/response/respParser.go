@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -25,24 +27,82 @@ type Resp struct {
 	Size    int
 	Words   int
 	Lines   int
+	Proto   string // the negotiated protocol such as HTTP/1.1 or HTTP/2.0
+	Request ReqInfo
+	Vars    map[string]string // the variables extracted from this response
+}
+
+// ReqInfo describes the fully processed request that produced a response
+type ReqInfo struct {
+	Url        string
+	Method     string
+	Headers    []string
+	Body       string
+	Transforms []string
+	Step       int    // the index of the request file when using multiple request files
+	Proxy      string // the proxy the request was sent through, without its password
+	Raw        bool   // the body is the whole request when it was sent as is
+}
+
+// NewResp builds a new response object from its parts
+func NewResp(code int, headers []string, body string, respTime int, proto string) *Resp {
+	r := Resp{
+		Code:    code,
+		Time:    respTime,
+		Body:    body,
+		Headers: headers,
+		Proto:   proto,
+	}
+	r.Size, r.Words, r.Lines = sizeRespBody(r.Body)
+	return &r
 }
 
 // NewRespFromTcp builds a new response object from a tcp response message
 func NewRespFromTcp(rawResp string, respTime int) *Resp {
 	r := Resp{
-		Code: getTcpRespCode(rawResp),
-		Time: respTime,
-		Body: tcpRespToRespBody(rawResp),
+		Code:    getTcpRespCode(rawResp),
+		Time:    respTime,
+		Body:    tcpRespToRespBody(rawResp),
+		Headers: tcpRespToHeaders(rawResp),
+	}
+	// responses that aren't http are kept whole
+	if r.Code == 0 {
+		r.Body = rawResp
+		r.Headers = []string{}
+	} else {
+		r.Proto = strings.Fields(rawResp)[0]
 	}
 	r.Size, r.Words, r.Lines = sizeRespBody(r.Body)
 	return &r
 }
 
+// NewRespFromString builds a response object from the output of Resp.ToString, anything that isn't in that format is
+// treated as the body
+func NewRespFromString(str string) *Resp {
+	lines := strings.Split(str, "\n")
+	code, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return NewResp(0, []string{}, str, 0, "")
+	}
+	headers := []string{}
+	i := 1
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		headers = append(headers, lines[i])
+	}
+	body := ""
+	if i+1 < len(lines) {
+		body = strings.Join(lines[i+1:], "\n")
+	}
+	return NewResp(code, headers, body, 0, "")
+}
+
 // NewRespFromHttp builds a new response object from a http response
 func NewRespFromHttp(resp *http.Response, respTime int, err error) *Resp {
 	statusCode := 0
+	proto := ""
 	if resp != nil {
 		statusCode = resp.StatusCode
+		proto = resp.Proto
 	}
 	// parse code from error message
 	if err != nil {
@@ -65,6 +125,7 @@ func NewRespFromHttp(resp *http.Response, respTime int, err error) *Resp {
 		Body:    httpRespToRespBody(resp),
 		Headers: httpRespToHeaders(resp),
 		Err:     err,
+		Proto:   proto,
 	}
 	r.Size, r.Words, r.Lines = sizeRespBody(r.Body)
 	return &r
@@ -79,6 +140,121 @@ func (r *Resp) ToString() string {
 	return res
 }
 
+// HeaderValue returns the value of the header with the given name, ignoring case.
+// If the header appears multiple times the values are joined with new lines
+func (r *Resp) HeaderValue(name string) string {
+	values := []string{}
+	for _, header := range r.Headers {
+		split := strings.SplitN(header, ":", 2)
+		if len(split) == 2 && strings.EqualFold(strings.TrimSpace(split[0]), name) {
+			values = append(values, strings.TrimSpace(split[1]))
+		}
+	}
+	return strings.Join(values, "\n")
+}
+
+// Cookie returns the value of a cookie set by the response. If the cookie is set more than once the last value is
+// returned. Cookie headers are also checked so request headers can be read the same way
+func (r *Resp) Cookie(name string) (string, bool) {
+	value, found := "", false
+	for _, header := range r.Headers {
+		split := strings.SplitN(header, ":", 2)
+		if len(split) != 2 {
+			continue
+		}
+		headerName := strings.TrimSpace(split[0])
+		if strings.EqualFold(headerName, "Set-Cookie") {
+			cookie, err := http.ParseSetCookie(strings.TrimSpace(split[1]))
+			if err == nil && cookie.Name == name {
+				value, found = cookie.Value, true
+			}
+		} else if strings.EqualFold(headerName, "Cookie") {
+			cookies, _ := http.ParseCookie(strings.TrimSpace(split[1]))
+			for _, cookie := range cookies {
+				if cookie.Name == name {
+					value, found = cookie.Value, true
+				}
+			}
+		}
+	}
+	return value, found
+}
+
+// target gets the method, request target, host and headers of the request. For http2 request files they come from
+// the :method, :path and :authority pseudo-headers, which are left out of the headers
+func (r *ReqInfo) target() (method string, path string, host string, headers []string, http2 bool) {
+	method, path = r.Method, r.Url
+	parsedUrl, err := url.Parse(r.Url)
+	if err == nil {
+		path = parsedUrl.RequestURI()
+		host = parsedUrl.Host
+	}
+	headers = []string{}
+	for _, header := range r.Headers {
+		if !strings.HasPrefix(header, ":") {
+			headers = append(headers, header)
+			continue
+		}
+		http2 = true
+		name, value, _ := strings.Cut(header[1:], ":")
+		value = strings.TrimSpace(value)
+		switch name {
+		case "method":
+			method = value
+		case "path":
+			path = value
+		case "authority":
+			host = value
+		}
+	}
+	return method, path, host, headers, http2
+}
+
+// ToString formats the request as a raw http request like the ones saved by BurpSuite
+func (r *ReqInfo) ToString() string {
+	if r.Raw {
+		return r.Body
+	}
+	method, path, host, headers, http2 := r.target()
+	proto := "HTTP/1.1"
+	if http2 {
+		proto = "HTTP/2"
+	}
+	res := fmt.Sprintf("%s %s %s\r\n", method, path, proto)
+	hasHost := false
+	for _, header := range headers {
+		if strings.HasPrefix(strings.ToLower(header), "host:") {
+			hasHost = true
+		}
+	}
+	if !hasHost && host != "" {
+		res += "Host: " + host + "\r\n"
+	}
+	for _, header := range headers {
+		res += header + "\r\n"
+	}
+	return res + "\r\n" + r.Body
+}
+
+// ToResult converts the response into a result for the output file
+func (r *Resp) ToResult(positions []string) utils.Result {
+	return utils.Result{
+		Code:       r.Code,
+		Size:       r.Size,
+		Words:      r.Words,
+		Lines:      r.Lines,
+		Time:       r.Time,
+		Positions:  positions,
+		Transforms: r.Request.Transforms,
+		Url:        r.Request.Url,
+		Method:     r.Request.Method,
+		Step:       r.Request.Step,
+		Headers:    r.Headers,
+		Proxy:      r.Request.Proxy,
+		Proto:      r.Proto,
+	}
+}
+
 // IsRecurse determines if a value response code corresponds to a web folder
 func (r *Resp) IsRecurse(codes []int) bool {
 	ret := false
@@ -126,15 +302,50 @@ func (resp *Resp) ProcessResp(positions []string, counter *utils.Counter, args *
 
 	filter := NewFilter(resp)
 	passed := filter.ApplyFilters(&args.FilterOptions)
+	// only the chosen steps of the request chain can be hits
+	if len(args.ChainOptions.HitStep) > 0 && !slices.Contains(args.ChainOptions.HitStep, resp.Request.Step) {
+		passed = false
+	}
+	// responses that get their step repeated by -until or abort the chain with -abort-if are misses
+	if matched, found := MatchStep(args.ChainOptions.Until, resp.Request.Step, resp, false); found && !matched {
+		passed = false
+	}
+	if matched, _ := MatchStep(args.ChainOptions.AbortIf, resp.Request.Step, resp, true); matched {
+		passed = false
+	}
 	if passed {
 		args.OutputOptions.Logger.Test("Passed all filters: " + strconv.FormatBool(passed))
+		var displayPos []string
 		if len(positions) > 0 {
-			displayPos := make([]string, len(positions))
+			displayPos = make([]string, len(positions))
 			copy(displayPos, positions)
 			displayPos[args.RecursionOptions.RecursePosition] = strings.Join(utils.FrontierQ[0], "") + positions[args.RecursionOptions.RecursePosition]
 			args.OutputOptions.Logger.Println(respLineFormatter(resp.Code, resp.Size, resp.Words, resp.Lines, resp.Time, displayPos, 12))
 		}
-		utils.PrintProgress(counter, args.GeneralOptions.Dos, args.OutputOptions.Logger)
+		if args.OutputOptions.Results != nil {
+			err := args.OutputOptions.Results.Write(resp.ToResult(displayPos))
+			if err != nil {
+				args.OutputOptions.Logger.Printf("\r\033[KError writing result to output file: %s\n", err.Error())
+			}
+		}
+		if args.OutputOptions.Report != nil {
+			args.OutputOptions.Report.Add(resp.ToResult(displayPos), resp.Request.ToString(), resp.ToString())
+		}
+		if args.OutputOptions.Replayer != nil {
+			method, reqUrl, headers, body, err := resp.Request.Parts()
+			if err != nil {
+				args.OutputOptions.Logger.Printf("\r\033[KError replaying %s: %s\n", resp.Request.Url, err.Error())
+			} else {
+				args.OutputOptions.Replayer.Replay(method, reqUrl, headers, body)
+			}
+		}
+		if args.OutputOptions.Saver != nil {
+			err := args.OutputOptions.Saver.Save(displayPos, resp.Request.Step, resp.Request.ToString(), resp.ToRaw(), resp.Request.ToCurl())
+			if err != nil {
+				args.OutputOptions.Logger.Printf("\r\033[KError saving request: %s\n", err.Error())
+			}
+		}
+		utils.PrintProgress(counter, args.GeneralOptions.Dos, args.RequestOptions.Limiter, args.OutputOptions.Logger)
 	}
 
 	if args.CaptureOptions.Cap != "" {
@@ -204,15 +415,28 @@ func tcpRespToRespBody(resp string) string {
 	return matched[1]
 }
 
+// tcpRespToHeaders grabs the headers from the raw tcp response
+func tcpRespToHeaders(resp string) []string {
+	headers := []string{}
+	head, _, _ := strings.Cut(resp, "\r\n\r\n")
+	lines := strings.Split(head, "\r\n")
+	for _, line := range lines[1:] {
+		if strings.Contains(line, ":") {
+			headers = append(headers, line)
+		}
+	}
+	return headers
+}
+
 // getTcpRespCode parses the response code from a raw tcp response.
 // Returns the response code as a string
 func getTcpRespCode(resp string) int {
 	respRx := regexp.MustCompile(`HTTP/\S+\s(\d+)`)
 	match := respRx.FindStringSubmatch(resp)
-	var code string
-	if match != nil {
-		code = match[1]
+	if match == nil {
+		return 0
 	}
+	code := match[1]
 	intCode, err := strconv.Atoi(code)
 	if err != nil {
 		fmt.Printf("Error converting response code %s to int in getTcpRespCode(...)", code)
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckpointState is the progress of a run that gets saved to the state file.
+// Lines and Extension point to the first job that might not have been sent yet, jobs before it are skipped when resuming
+type CheckpointState struct {
+	Lines     []int      `json:"lines"`
+	Extension int        `json:"extension"`
+	FrontierQ [][]string `json:"frontier"`
+	Progress  int        `json:"progress"`
+}
+
+type checkpointJob struct {
+	lines     []int
+	extension int
+	done      bool
+}
+
+// Checkpoint keeps track of the jobs that have been sent to the request threads so the run can be resumed later.
+// Since jobs finish out of order, the saved position is the oldest job that hasn't finished yet
+type Checkpoint struct {
+	fname    string
+	resume   *CheckpointState
+	jobs     []*checkpointJob
+	pending  map[string][]*checkpointJob
+	next     checkpointJob
+	progress int
+	lock     sync.Mutex
+}
+
+func NewCheckpoint(fname string, resume *CheckpointState) *Checkpoint {
+	c := &Checkpoint{
+		fname:   fname,
+		resume:  resume,
+		pending: map[string][]*checkpointJob{},
+	}
+	if resume != nil {
+		c.progress = resume.Progress
+		c.next = checkpointJob{lines: resume.Lines, extension: resume.Extension}
+	}
+	return c
+}
+
+// File gets the name of the state file
+func (c *Checkpoint) File() string {
+	return c.fname
+}
+
+// LoadCheckpoint reads a saved state file
+func LoadCheckpoint(fname string) (*CheckpointState, error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var state CheckpointState
+	err = json.Unmarshal(content, &state)
+	return &state, err
+}
+
+func checkpointKey(positions []string) string {
+	return strings.Join(positions, "\x00")
+}
+
+// TakeResume returns the position to resume from and clears it, so it only applies to the first directory
+// that gets fuzzed
+func (c *Checkpoint) TakeResume() *CheckpointState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	resume := c.resume
+	c.resume = nil
+	return resume
+}
+
+// Dispatch records a job right before it is sent to the request threads
+func (c *Checkpoint) Dispatch(positions []string, lines []int, extension int) {
+	job := &checkpointJob{
+		lines:     append([]int{}, lines...),
+		extension: extension,
+	}
+	key := checkpointKey(positions)
+	c.lock.Lock()
+	c.jobs = append(c.jobs, job)
+	c.pending[key] = append(c.pending[key], job)
+	c.next = checkpointJob{lines: job.lines, extension: extension + 1}
+	c.lock.Unlock()
+}
+
+// Done marks the oldest unfinished job with the same positions as finished
+func (c *Checkpoint) Done(positions []string) {
+	key := checkpointKey(positions)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	jobs := c.pending[key]
+	if len(jobs) <= 0 {
+		return
+	}
+	jobs[0].done = true
+	if len(jobs) > 1 {
+		c.pending[key] = jobs[1:]
+	} else {
+		delete(c.pending, key)
+	}
+	// drop all finished jobs from the front of the queue
+	for len(c.jobs) > 0 && c.jobs[0].done {
+		c.jobs = c.jobs[1:]
+		c.progress++
+	}
+}
+
+// Reset clears the tracked jobs when moving on to the next recursion directory
+func (c *Checkpoint) Reset() {
+	c.lock.Lock()
+	c.jobs = nil
+	c.pending = map[string][]*checkpointJob{}
+	c.next = checkpointJob{}
+	c.progress = 0
+	c.resume = nil
+	c.lock.Unlock()
+}
+
+// State returns the current position of the run
+func (c *Checkpoint) State() CheckpointState {
+	c.lock.Lock()
+	job := c.next
+	if len(c.jobs) > 0 {
+		job = *c.jobs[0]
+	}
+	state := CheckpointState{
+		Lines:     job.lines,
+		Extension: job.extension,
+		Progress:  c.progress,
+	}
+	c.lock.Unlock()
+
+	FrontierLock.Lock()
+	for _, base := range FrontierQ {
+		state.FrontierQ = append(state.FrontierQ, append([]string{}, base...))
+	}
+	FrontierLock.Unlock()
+	return state
+}
+
+// Save writes the current position to the state file. The file is replaced in one step so an interrupted
+// save never leaves a corrupt state file behind
+func (c *Checkpoint) Save() error {
+	content, err := json.MarshalIndent(c.State(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpName := c.fname + ".tmp"
+	err = os.WriteFile(tmpName, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpName, c.fname)
+}
+
+// SaveLoop saves the state file periodically
+func (c *Checkpoint) SaveLoop(interval time.Duration, log *Logger) {
+	for {
+		time.Sleep(interval)
+		err := c.Save()
+		if err != nil {
+			log.Printf("\r\033[KError saving checkpoint: %s\n", err.Error())
+		}
+	}
+}
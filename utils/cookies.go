@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileCookie is a cookie loaded from a cookie file along with the host it belongs to
+type FileCookie struct {
+	Host   string
+	Cookie *http.Cookie
+}
+
+// LoadCookieFile reads cookies from a Netscape cookie file, the format used by curl, wget and most browser extensions
+func LoadCookieFile(fname string) ([]FileCookie, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cookies := []FileCookie{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab separated fields", lineNum)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %s", lineNum, fields[4])
+		}
+		cookie := &http.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    strings.Join(fields[6:], "\t"),
+			HttpOnly: httpOnly,
+		}
+		// cookies that are only sent to the exact host don't have a domain
+		if !strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = ""
+		}
+		// an expiry of 0 is a session cookie
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, FileCookie{Host: strings.TrimPrefix(fields[0], "."), Cookie: cookie})
+	}
+	return cookies, scanner.Err()
+}
+
+// NewCookieJar creates a cookie jar holding the seed cookies
+func NewCookieJar(seed []FileCookie) http.CookieJar {
+	jar, _ := cookiejar.New(nil)
+	for _, c := range seed {
+		scheme := "http"
+		if c.Cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: c.Host, Path: "/"}, []*http.Cookie{c.Cookie})
+	}
+	return jar
+}
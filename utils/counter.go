@@ -55,6 +55,14 @@ func (c *Counter) Reset() {
 	c.counterLock.Unlock()
 }
 
+// SetCount sets the request progress counter, used when resuming a run
+func (c *Counter) SetCount(count int) {
+	c.counterLock.Lock()
+	c.counter = count
+	c.counterPrev = count
+	c.counterLock.Unlock()
+}
+
 // CounterInc increments the request progress counter
 func (c *Counter) CounterInc() {
 	c.counterLock.Lock()
@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the character sets that can be used in masks, the same as hashcat's built in charsets
+var maskCharsets = map[rune]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+func init() {
+	maskCharsets['a'] = maskCharsets['l'] + maskCharsets['u'] + maskCharsets['d'] + maskCharsets['s']
+}
+
+// generator produces the words of a virtual wordlist one at a time
+type generator interface {
+	next() (string, bool)
+	count() (int, error)
+}
+
+// IsGeneratorWordlist returns true if the wordlist is generated instead of read from a file
+func IsGeneratorWordlist(name string) bool {
+	return strings.HasPrefix(name, "range:") || strings.HasPrefix(name, "charset:") || strings.HasPrefix(name, "mask:")
+}
+
+// newGenerator parses a virtual wordlist:
+// range:<start>-<end>[:<width>] counts from start to end, zero padding the numbers to width
+// charset:<chars>:<min>-<max> every combination of the characters with a length from min to max
+// mask:<mask> every word matching a hashcat style mask like ?u?l?l?d
+func newGenerator(name string) (generator, error) {
+	kind, spec, _ := strings.Cut(name, ":")
+	switch kind {
+	case "range":
+		return newRangeGenerator(spec)
+	case "charset":
+		return newCharsetGenerator(spec)
+	case "mask":
+		return newMaskGenerator(spec)
+	}
+	return nil, errors.New("unknown generator " + kind)
+}
+
+// rangeGenerator counts through a range of numbers
+type rangeGenerator struct {
+	start   int
+	end     int
+	width   int
+	current int
+	step    int
+}
+
+func newRangeGenerator(spec string) (*rangeGenerator, error) {
+	parts := strings.Split(spec, ":")
+	// the - between the bounds is the first one after the start, which can be negative like -5-5 or -10--1
+	sep := -1
+	if len(parts[0]) > 1 {
+		sep = strings.Index(parts[0][1:], "-")
+	}
+	if len(parts) > 2 || sep < 0 {
+		return nil, fmt.Errorf("invalid range %s, expected range:<start>-<end>[:<width>]", spec)
+	}
+	startStr, endStr := parts[0][:sep+1], parts[0][sep+2:]
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range start %s", startStr)
+	}
+	end, err := strconv.Atoi(endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range end %s", endStr)
+	}
+	g := &rangeGenerator{start: start, end: end, current: start, step: 1}
+	if end < start {
+		g.step = -1
+	}
+	if len(parts) > 1 {
+		g.width, err = strconv.Atoi(parts[1])
+		if err != nil || g.width < 0 {
+			return nil, fmt.Errorf("invalid range width %s", parts[1])
+		}
+	}
+	return g, nil
+}
+
+func (g *rangeGenerator) next() (string, bool) {
+	if (g.step > 0 && g.current > g.end) || (g.step < 0 && g.current < g.end) {
+		return "", false
+	}
+	word := fmt.Sprintf("%0*d", g.width, g.current)
+	g.current += g.step
+	return word, true
+}
+
+func (g *rangeGenerator) count() (int, error) {
+	return (g.end-g.start)*g.step + 1, nil
+}
+
+// odometer goes through every combination of one character from each set, the last set changes fastest
+type odometer struct {
+	sets    [][]rune
+	indexes []int
+	done    bool
+}
+
+func newOdometer(sets [][]rune) *odometer {
+	o := &odometer{sets: sets, indexes: make([]int, len(sets))}
+	for _, set := range sets {
+		o.done = o.done || len(set) == 0
+	}
+	return o
+}
+
+func (o *odometer) next() (string, bool) {
+	if o.done {
+		return "", false
+	}
+	word := make([]rune, len(o.sets))
+	for i, set := range o.sets {
+		word[i] = set[o.indexes[i]]
+	}
+	// move to the next combination
+	o.done = true
+	for i := len(o.indexes) - 1; i >= 0; i-- {
+		o.indexes[i]++
+		if o.indexes[i] < len(o.sets[i]) {
+			o.done = false
+			break
+		}
+		o.indexes[i] = 0
+	}
+	return string(word), true
+}
+
+func (o *odometer) count() (int, error) {
+	total := 1
+	for _, set := range o.sets {
+		if len(set) > 0 && total > math.MaxInt/len(set) {
+			return 0, errors.New("too many words to generate")
+		}
+		total *= len(set)
+	}
+	return total, nil
+}
+
+// charsetGenerator goes through every combination of a set of characters for each length
+type charsetGenerator struct {
+	chars    []rune
+	length   int
+	max      int
+	odometer *odometer
+}
+
+func newCharsetGenerator(spec string) (*charsetGenerator, error) {
+	idx := strings.LastIndex(spec, ":")
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid charset %s, expected charset:<chars>:<min>-<max>", spec)
+	}
+	chars := []rune(spec[:idx])
+	low, high, found := strings.Cut(spec[idx+1:], "-")
+	if !found {
+		high = low
+	}
+	min, err := strconv.Atoi(low)
+	if err != nil || min < 1 {
+		return nil, fmt.Errorf("invalid charset length %s", spec[idx+1:])
+	}
+	max, err := strconv.Atoi(high)
+	if err != nil || max < min {
+		return nil, fmt.Errorf("invalid charset length %s", spec[idx+1:])
+	}
+	g := &charsetGenerator{chars: chars, length: min, max: max}
+	g.odometer = g.odometerForLength(min)
+	return g, nil
+}
+
+func (g *charsetGenerator) odometerForLength(length int) *odometer {
+	sets := make([][]rune, length)
+	for i := range sets {
+		sets[i] = g.chars
+	}
+	return newOdometer(sets)
+}
+
+func (g *charsetGenerator) next() (string, bool) {
+	word, ok := g.odometer.next()
+	for !ok && g.length < g.max {
+		g.length++
+		g.odometer = g.odometerForLength(g.length)
+		word, ok = g.odometer.next()
+	}
+	return word, ok
+}
+
+func (g *charsetGenerator) count() (int, error) {
+	total := 0
+	for length := g.length; length <= g.max; length++ {
+		c, err := g.odometerForLength(length).count()
+		if err != nil || total > math.MaxInt-c {
+			return 0, errors.New("too many words to generate")
+		}
+		total += c
+	}
+	return total, nil
+}
+
+// newMaskGenerator parses a hashcat style mask, ?? is a literal question mark
+func newMaskGenerator(spec string) (*odometer, error) {
+	mask := []rune(spec)
+	sets := [][]rune{}
+	for i := 0; i < len(mask); i++ {
+		if mask[i] != '?' {
+			sets = append(sets, []rune{mask[i]})
+			continue
+		}
+		if i+1 >= len(mask) {
+			return nil, fmt.Errorf("invalid mask %s, ? at the end of the mask", spec)
+		}
+		i++
+		if mask[i] == '?' {
+			sets = append(sets, []rune{'?'})
+			continue
+		}
+		charset, ok := maskCharsets[mask[i]]
+		if !ok {
+			return nil, fmt.Errorf("invalid mask %s, unknown charset ?%c", spec, mask[i])
+		}
+		sets = append(sets, []rune(charset))
+	}
+	if len(sets) <= 0 {
+		return nil, errors.New("empty mask")
+	}
+	return newOdometer(sets), nil
+}
+
+// generatorReader turns a generator into a wordlist with one word per line
+type generatorReader struct {
+	gen generator
+	buf []byte
+}
+
+func (r *generatorReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		word, ok := r.gen.next()
+		if !ok {
+			break
+		}
+		r.buf = append(r.buf, word...)
+		r.buf = append(r.buf, '\n')
+	}
+	if len(r.buf) <= 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *generatorReader) Close() error {
+	return nil
+}
+
+// GeneratorLen returns the number of words a virtual wordlist generates
+func GeneratorLen(name string) (int, error) {
+	gen, err := newGenerator(name)
+	if err != nil {
+		return 0, err
+	}
+	return gen.count()
+}
@@ -0,0 +1,384 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a single selector in a json path, like .name, [0], [*], [1:3] or [?(@.id==1)]
+type jsonPathStep struct {
+	recursive bool
+	wildcard  bool
+	name      *string
+	index     *int
+	slice     []*int
+	filter    *jsonPathFilter
+}
+
+// jsonPathFilter keeps the elements where the path compares to the value, or where the path exists if there is no
+// operator
+type jsonPathFilter struct {
+	path  []jsonPathStep
+	op    string
+	value any
+}
+
+// JsonPath is a parsed json path expression
+type JsonPath struct {
+	steps []jsonPathStep
+}
+
+// ParseJsonPath parses a json path such as $.data.users[0].name, $..id, $.items[*] or $.items[?(@.type=='admin')].
+// The leading $ is optional
+func ParseJsonPath(path string) (*JsonPath, error) {
+	path = strings.TrimSpace(path)
+	if len(path) >= 2 && (path[0] == '"' || path[0] == '\'') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	}
+	steps, err := parseJsonPathSteps(path, '$')
+	if err != nil {
+		return nil, err
+	}
+	return &JsonPath{steps: steps}, nil
+}
+
+func parseJsonPathSteps(path string, root byte) ([]jsonPathStep, error) {
+	if strings.HasPrefix(path, string(root)) {
+		path = path[1:]
+	} else if path != "" && path[0] != '.' && path[0] != '[' {
+		path = "." + path
+	}
+	steps := []jsonPathStep{}
+	for i := 0; i < len(path); {
+		step := jsonPathStep{}
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			step.recursive = true
+			i += 2
+		case path[i] == '.':
+			i++
+		case path[i] != '[':
+			return nil, fmt.Errorf("unexpected %c at %d in json path %s", path[i], i, path)
+		}
+		if i >= len(path) {
+			return nil, errors.New("json path " + path + " ends with a .")
+		}
+		if path[i] != '[' {
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			name := path[i : i+end]
+			if name == "*" {
+				step.wildcard = true
+			} else if name == "" {
+				return nil, errors.New("empty name in json path " + path)
+			} else {
+				step.name = &name
+			}
+			i += end
+			steps = append(steps, step)
+			continue
+		}
+		end := jsonPathBracketEnd(path, i)
+		if end < 0 {
+			return nil, errors.New("unclosed [ in json path " + path)
+		}
+		err := parseJsonPathBracket(path[i+1:end], &step)
+		if err != nil {
+			return nil, err
+		}
+		i = end + 1
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// jsonPathBracketEnd finds the ] closing the [ at start, skipping brackets inside quotes and filters
+func jsonPathBracketEnd(path string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJsonPathBracket(content string, step *jsonPathStep) error {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "*":
+		step.wildcard = true
+	case len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0]:
+		name := content[1 : len(content)-1]
+		step.name = &name
+	case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+		filter, err := parseJsonPathFilter(content[2 : len(content)-1])
+		if err != nil {
+			return err
+		}
+		step.filter = filter
+	case strings.Contains(content, ":"):
+		for _, part := range strings.SplitN(content, ":", 2) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				step.slice = append(step.slice, nil)
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid slice [%s] in json path", content)
+			}
+			step.slice = append(step.slice, &n)
+		}
+	default:
+		n, err := strconv.Atoi(content)
+		if err != nil {
+			return fmt.Errorf("invalid index [%s] in json path", content)
+		}
+		step.index = &n
+	}
+	return nil
+}
+
+func parseJsonPathFilter(expr string) (*jsonPathFilter, error) {
+	expr = strings.TrimSpace(expr)
+	filter := &jsonPathFilter{}
+	left := expr
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		idx := strings.Index(expr, op)
+		if idx < 0 {
+			continue
+		}
+		filter.op = op
+		left = strings.TrimSpace(expr[:idx])
+		right := strings.TrimSpace(expr[idx+len(op):])
+		if len(right) >= 2 && (right[0] == '\'' || right[0] == '"') && right[len(right)-1] == right[0] {
+			filter.value = right[1 : len(right)-1]
+		} else if err := json.Unmarshal([]byte(right), &filter.value); err != nil {
+			return nil, fmt.Errorf("invalid value %s in json path filter %s", right, expr)
+		}
+		break
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("json path filter %s must start with @", expr)
+	}
+	steps, err := parseJsonPathSteps(left, '@')
+	if err != nil {
+		return nil, err
+	}
+	filter.path = steps
+	return filter, nil
+}
+
+// Find returns every value in the document matched by the path
+func (p *JsonPath) Find(doc any) []any {
+	return findJsonPath(p.steps, []any{doc})
+}
+
+func findJsonPath(steps []jsonPathStep, nodes []any) []any {
+	for _, step := range steps {
+		next := []any{}
+		for _, node := range nodes {
+			if step.recursive {
+				for _, descendant := range jsonDescendants(node) {
+					next = append(next, step.apply(descendant)...)
+				}
+			} else {
+				next = append(next, step.apply(node)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// jsonChildren returns the values of an object in key order or the elements of an array
+func jsonChildren(node any) []any {
+	switch n := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		children := []any{}
+		for _, key := range keys {
+			children = append(children, n[key])
+		}
+		return children
+	case []any:
+		return n
+	}
+	return nil
+}
+
+// jsonDescendants returns the node and everything nested inside it
+func jsonDescendants(node any) []any {
+	nodes := []any{node}
+	for _, child := range jsonChildren(node) {
+		nodes = append(nodes, jsonDescendants(child)...)
+	}
+	return nodes
+}
+
+func (s *jsonPathStep) apply(node any) []any {
+	switch {
+	case s.wildcard:
+		return jsonChildren(node)
+	case s.name != nil:
+		if obj, ok := node.(map[string]any); ok {
+			if value, found := obj[*s.name]; found {
+				return []any{value}
+			}
+		}
+	case s.index != nil:
+		if arr, ok := node.([]any); ok {
+			idx := *s.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				return []any{arr[idx]}
+			}
+		}
+	case s.slice != nil:
+		if arr, ok := node.([]any); ok {
+			bounds := []int{0, len(arr)}
+			for i, bound := range s.slice {
+				if bound == nil {
+					continue
+				}
+				bounds[i] = *bound
+				if bounds[i] < 0 {
+					bounds[i] += len(arr)
+				}
+				bounds[i] = max(0, min(len(arr), bounds[i]))
+			}
+			if bounds[0] < bounds[1] {
+				return arr[bounds[0]:bounds[1]]
+			}
+		}
+	case s.filter != nil:
+		matched := []any{}
+		for _, child := range jsonChildren(node) {
+			if s.filter.matches(child) {
+				matched = append(matched, child)
+			}
+		}
+		return matched
+	}
+	return nil
+}
+
+func (f *jsonPathFilter) matches(node any) bool {
+	values := findJsonPath(f.path, []any{node})
+	if f.op == "" {
+		return len(values) > 0
+	}
+	for _, value := range values {
+		if compareJson(value, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareJson(left any, op string, right any) bool {
+	if l, ok := left.(json.Number); ok {
+		left, _ = l.Float64()
+	}
+	lNum, lIsNum := left.(float64)
+	rNum, rIsNum := right.(float64)
+	if lIsNum && rIsNum {
+		switch op {
+		case "==":
+			return lNum == rNum
+		case "!=":
+			return lNum != rNum
+		case "<":
+			return lNum < rNum
+		case "<=":
+			return lNum <= rNum
+		case ">":
+			return lNum > rNum
+		case ">=":
+			return lNum >= rNum
+		}
+	}
+	lStr, lIsStr := left.(string)
+	rStr, rIsStr := right.(string)
+	if lIsStr && rIsStr {
+		switch op {
+		case "<":
+			return lStr < rStr
+		case "<=":
+			return lStr <= rStr
+		case ">":
+			return lStr > rStr
+		case ">=":
+			return lStr >= rStr
+		}
+	}
+	switch op {
+	case "==":
+		return fmt.Sprint(left) == fmt.Sprint(right)
+	case "!=":
+		return fmt.Sprint(left) != fmt.Sprint(right)
+	}
+	return false
+}
+
+// JsonPathString runs a json path on a json document. A single string is returned as is, anything else is
+// formatted as json and multiple matches are returned as a json array. Returns false if nothing matched
+func JsonPathString(doc string, path string) (string, bool, error) {
+	p, err := ParseJsonPath(path)
+	if err != nil {
+		return "", false, err
+	}
+	return p.FindString(doc)
+}
+
+// FindString runs the path on a json document and formats the result like JsonPathString
+func (p *JsonPath) FindString(doc string) (string, bool, error) {
+	var parsed any
+	decoder := json.NewDecoder(strings.NewReader(doc))
+	// keep numbers as they were written so large ids don't lose precision
+	decoder.UseNumber()
+	err := decoder.Decode(&parsed)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid json (%s)", err.Error())
+	}
+	values := p.Find(parsed)
+	if len(values) <= 0 {
+		return "", false, nil
+	}
+	var result any = values
+	if len(values) == 1 {
+		result = values[0]
+	}
+	if str, ok := result.(string); ok {
+		return str, true, nil
+	}
+	out, err := json.Marshal(result)
+	return string(out), true, err
+}
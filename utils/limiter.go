@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the slowest the limiter will go in req/s
+	minRate = 0.5
+	// how much the rate is cut by on a throttling signal
+	backOffFactor = 0.5
+	// responses to requests that were already in flight when the rate was cut shouldn't cut it again
+	backOffCooldown = time.Second
+	// how often the rate goes up while the responses are healthy, and by how much of the ceiling
+	rampUpInterval = time.Second
+	rampUpStep     = 0.05
+	// the longest a Retry-After header can pause the run for
+	maxRetryAfter = 5 * time.Minute
+	// the latency needs this many healthy responses before rising latency counts as throttling
+	minLatencySamples = 20
+	// responses faster than this never count as slow, so fast local servers don't look throttled
+	minSlowLatency = 200
+)
+
+// RateLimiter is a rate limit shared by all the workers that adapts to the server. It cuts the rate in half when the
+// server throttles (a throttling response code, a Retry-After header or latency rising well above normal) and adds a
+// little back every second while the responses are healthy, up to the -rate if there is one
+type RateLimiter struct {
+	lock sync.Mutex
+
+	rate          float64 // the current rate in req/s, 0 means no limit
+	maxRate       float64 // the rate to ramp back up to, 0 means no limit
+	ceiling       float64 // the rate the ramp up is measured against
+	codes         []int
+	latencyFactor float64
+
+	next        time.Time // when the next request can be sent
+	pausedUntil time.Time // when the server asked to retry with Retry-After
+	lastBackOff time.Time
+	lastRampUp  time.Time
+
+	baseline float64 // the average latency of healthy responses in ms
+	samples  int
+
+	// the responses received in the current second, used to find the rate to start from when there's no limit yet
+	windowStart time.Time
+	windowCount int
+	measured    float64
+}
+
+// NewRateLimiter creates a limiter that starts at maxRate, or without a limit if maxRate is 0. Responses with one of
+// the codes or a latency more than latencyFactor times the normal latency are throttling signals, a latencyFactor of
+// 0 ignores the latency
+func NewRateLimiter(maxRate float64, codes []int, latencyFactor float64) *RateLimiter {
+	return &RateLimiter{
+		rate:          maxRate,
+		maxRate:       maxRate,
+		ceiling:       maxRate,
+		codes:         codes,
+		latencyFactor: latencyFactor,
+		windowStart:   time.Now(),
+	}
+}
+
+// Wait blocks until the next request can be sent
+func (l *RateLimiter) Wait() {
+	l.lock.Lock()
+	now := time.Now()
+	start := now
+	if l.pausedUntil.After(start) {
+		start = l.pausedUntil
+	}
+	if l.rate > 0 {
+		if l.next.After(start) {
+			start = l.next
+		}
+		l.next = start.Add(time.Duration(float64(time.Second) / l.rate))
+	}
+	l.lock.Unlock()
+	time.Sleep(start.Sub(now))
+}
+
+// Rate gets the current rate in req/s, 0 means there's no limit
+func (l *RateLimiter) Rate() float64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.rate
+}
+
+// PausedFor gets how long requests are paused for because of a Retry-After header
+func (l *RateLimiter) PausedFor() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return max(0, time.Until(l.pausedUntil))
+}
+
+// Observe adjusts the rate from a response, latency is in ms
+func (l *RateLimiter) Observe(code int, retryAfter string, latency int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	if elapsed := now.Sub(l.windowStart); elapsed >= time.Second {
+		l.measured = float64(l.windowCount) / elapsed.Seconds()
+		l.windowStart = now
+		l.windowCount = 0
+	}
+	l.windowCount++
+
+	throttled := slices.Contains(l.codes, code)
+	if wait := parseRetryAfter(retryAfter, now); wait > 0 && (throttled || code >= 400) {
+		throttled = true
+		if until := now.Add(min(wait, maxRetryAfter)); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+	}
+	slow := l.latencyFactor > 0 && l.samples >= minLatencySamples && latency >= minSlowLatency && float64(latency) > l.baseline*l.latencyFactor
+	if !throttled && !slow {
+		// a slow moving average so a few slow responses don't shift what normal is
+		if l.samples == 0 {
+			l.baseline = float64(latency)
+		} else {
+			l.baseline += (float64(latency) - l.baseline) * 0.05
+		}
+		l.samples++
+		l.rampUp(now)
+		return
+	}
+	l.backOff(now)
+}
+
+func (l *RateLimiter) backOff(now time.Time) {
+	if l.rate > 0 && now.Sub(l.lastBackOff) < backOffCooldown {
+		return
+	}
+	if l.rate <= 0 {
+		// start from the rate the requests were going at and ramp back up to it
+		current := l.measured
+		if current <= 0 {
+			current = float64(l.windowCount) / max(now.Sub(l.windowStart).Seconds(), 1)
+		}
+		l.rate = current
+		l.ceiling = current
+	}
+	l.rate = max(minRate, l.rate*backOffFactor)
+	l.lastBackOff = now
+}
+
+func (l *RateLimiter) rampUp(now time.Time) {
+	if l.rate <= 0 || (l.maxRate > 0 && l.rate >= l.maxRate) || now.Sub(l.lastBackOff) < rampUpInterval || now.Sub(l.lastRampUp) < rampUpInterval || now.Before(l.pausedUntil) {
+		return
+	}
+	l.rate += max(1, l.ceiling*rampUpStep)
+	l.lastRampUp = now
+	if l.rate >= l.ceiling {
+		// back to full speed, which is no limit at all without -rate
+		l.rate = l.maxRate
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of seconds or a date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
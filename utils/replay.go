@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replayer resends the requests of hits through a second proxy, like BurpSuite or ZAP, so they show up in its
+// history. Requests are sent in the background with their own client so they don't slow down the main run
+type Replayer struct {
+	proxy  string
+	client *http.Client
+	queue  chan *http.Request
+	wg     sync.WaitGroup
+	log    *Logger
+}
+
+// the number of hits that can wait to be replayed, hits after that are dropped so a slow proxy doesn't slow down the
+// main run
+const replayQueueSize = 100
+
+// NewReplayer creates the client for the replay proxy, supported proxies are http, https and socks5
+func NewReplayer(proxy string, timeout time.Duration, log *Logger) (*Replayer, error) {
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil || proxyUrl.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %s", proxy)
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy type %s in %s", proxyUrl.Scheme, proxy)
+	}
+	r := &Replayer{
+		proxy: proxy,
+		client: &http.Client{
+			Timeout: timeout,
+			// the proxy should see the same responses gohammer did
+			CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+			Transport: &http.Transport{
+				Proxy: http.ProxyURL(proxyUrl),
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true,
+				},
+			},
+		},
+		queue: make(chan *http.Request, replayQueueSize),
+		log:   log,
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+func (r *Replayer) run() {
+	defer r.wg.Done()
+	for req := range r.queue {
+		resp, err := r.client.Do(req)
+		if err != nil {
+			r.log.Printf("\r\033[KError replaying %s %s through %s: %s\n", req.Method, req.URL.String(), r.proxy, err.Error())
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// Replay queues a request to be resent through the proxy. Requests that can't be built or don't fit in the queue are
+// logged and skipped
+func (r *Replayer) Replay(method string, reqUrl string, headers []string, body string) {
+	req, err := http.NewRequest(method, reqUrl, strings.NewReader(body))
+	if err != nil {
+		r.log.Printf("\r\033[KError replaying %s %s: %s\n", method, reqUrl, err.Error())
+		return
+	}
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		switch {
+		case !found || strings.HasPrefix(name, ":") || strings.EqualFold(name, "Content-Length"):
+			// the length is worked out from the body and http2 pseudo-headers come from the url
+		case strings.EqualFold(name, "Host"):
+			req.Host = value
+		default:
+			req.Header.Add(name, value)
+		}
+	}
+	select {
+	case r.queue <- req:
+	default:
+		r.log.Printf("\r\033[KError replaying %s %s: the replay queue is full, %s is too slow\n", method, reqUrl, r.proxy)
+	}
+}
+
+// Close waits for the queued requests to be replayed
+func (r *Replayer) Close() {
+	close(r.queue)
+	r.wg.Wait()
+}
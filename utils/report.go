@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReportEntry is a result with the raw request and response that produced it
+type ReportEntry struct {
+	Result
+	Request  string
+	Response string
+}
+
+// Report collects results during a run and renders them into a single static html file once the run is done
+type Report struct {
+	fname   string
+	config  string
+	entries []ReportEntry
+	lock    sync.Mutex
+}
+
+type histogramBar struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+type reportPage struct {
+	Generated string
+	Config    string
+	Entries   []ReportEntry
+	Codes     []histogramBar
+	Sizes     []histogramBar
+}
+
+// NewReport creates a report that will be saved to fname. The config is shown as is in the report
+func NewReport(fname string, config string) *Report {
+	return &Report{
+		fname:  fname,
+		config: config,
+	}
+}
+
+// Add records a result in the report, it is safe to call from multiple threads
+func (r *Report) Add(result Result, request string, response string) {
+	r.lock.Lock()
+	r.entries = append(r.entries, ReportEntry{
+		Result:   result,
+		Request:  request,
+		Response: response,
+	})
+	r.lock.Unlock()
+}
+
+// Write renders all results collected so far into the report file
+func (r *Report) Write() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f, err := os.Create(r.fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	page := reportPage{
+		Generated: time.Now().Format(time.RFC1123),
+		Config:    r.config,
+		Entries:   r.entries,
+		Codes:     codeHistogram(r.entries),
+		Sizes:     sizeHistogram(r.entries, 10),
+	}
+	return reportTemplate.Execute(f, page)
+}
+
+// codeHistogram counts the results for each response code
+func codeHistogram(entries []ReportEntry) []histogramBar {
+	counts := map[int]int{}
+	for _, e := range entries {
+		counts[e.Code]++
+	}
+	codes := []int{}
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	bars := []histogramBar{}
+	for _, code := range codes {
+		bars = append(bars, histogramBar{Label: fmt.Sprintf("%d", code), Count: counts[code]})
+	}
+	return scaleHistogram(bars)
+}
+
+// sizeHistogram splits the response sizes into at most numBins equal width bins
+func sizeHistogram(entries []ReportEntry, numBins int) []histogramBar {
+	bars := []histogramBar{}
+	if len(entries) <= 0 {
+		return bars
+	}
+	sizes := []int{}
+	for _, e := range entries {
+		sizes = append(sizes, e.Size)
+	}
+	low := slices.Min(sizes)
+	high := slices.Max(sizes)
+	width := (high-low)/numBins + 1
+	counts := make([]int, (high-low)/width+1)
+	for _, s := range sizes {
+		counts[(s-low)/width]++
+	}
+	for i, count := range counts {
+		label := fmt.Sprintf("%d", low+i*width)
+		if width > 1 {
+			label = fmt.Sprintf("%d-%d", low+i*width, low+(i+1)*width-1)
+		}
+		bars = append(bars, histogramBar{Label: label, Count: count})
+	}
+	return scaleHistogram(bars)
+}
+
+// scaleHistogram sets the percentage of each bar relative to the largest bar
+func scaleHistogram(bars []histogramBar) []histogramBar {
+	highest := 0
+	for _, b := range bars {
+		highest = max(highest, b.Count)
+	}
+	for i := range bars {
+		if highest > 0 {
+			bars[i].Percent = bars[i].Count * 100 / highest
+		}
+	}
+	return bars
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Gohammer Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+.generated { color: #777; margin-top: 0.2em; }
+.charts { display: flex; gap: 3em; flex-wrap: wrap; }
+.chart { min-width: 300px; }
+.bar { display: flex; align-items: center; margin: 2px 0; font-size: 0.85em; }
+.bar .label { width: 8em; text-align: right; padding-right: 0.5em; }
+.bar .fill { background: #c0392b; height: 1em; margin-right: 0.5em; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+tr:nth-child(even) { background: #fafafa; }
+pre { background: #f4f4f4; padding: 0.5em; white-space: pre-wrap; word-break: break-all; max-height: 30em; overflow: auto; }
+input { padding: 4px; width: 30em; margin-bottom: 0.5em; }
+</style>
+</head>
+<body>
+<h1>Gohammer Report</h1>
+<p class="generated">Generated {{.Generated}} - {{len .Entries}} hits</p>
+<div class="charts">
+<div class="chart">
+<h2>Status Codes</h2>
+{{range .Codes}}<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{.Percent}}%"></span>{{.Count}}</div>
+{{end}}</div>
+<div class="chart">
+<h2>Sizes</h2>
+{{range .Sizes}}<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{.Percent}}%"></span>{{.Count}}</div>
+{{end}}</div>
+</div>
+<h2>Hits</h2>
+<input id="filter" type="text" placeholder="Filter hits, for example: 200 or admin">
+<table id="hits">
+<thead>
+<tr><th data-type="num">#</th><th data-type="num">Code</th><th data-type="num">Size</th><th data-type="num">Words</th><th data-type="num">Lines</th><th data-type="num">Time (ms)</th><th>Positions</th><th>Request</th></tr>
+</thead>
+<tbody>
+{{range $i, $e := .Entries}}<tr>
+<td>{{$i}}</td><td>{{$e.Code}}</td><td>{{$e.Size}}</td><td>{{$e.Words}}</td><td>{{$e.Lines}}</td><td>{{$e.Time}}</td>
+<td>{{range $e.Positions}}{{.}}<br>{{end}}</td>
+<td><details><summary>{{$e.Method}} {{$e.Url}}</summary><h4>Request</h4><pre>{{$e.Request}}</pre><h4>Response</h4><pre>{{$e.Response}}</pre></details></td>
+</tr>
+{{end}}</tbody>
+</table>
+<h2>Configuration</h2>
+<pre>{{.Config}}</pre>
+<script>
+var table = document.getElementById("hits");
+var body = table.tBodies[0];
+document.getElementById("filter").addEventListener("input", function (e) {
+	var term = e.target.value.toLowerCase();
+	for (var row of body.rows) {
+		row.style.display = row.textContent.toLowerCase().includes(term) ? "" : "none";
+	}
+});
+table.querySelectorAll("th").forEach(function (th, col) {
+	th.addEventListener("click", function () {
+		var asc = th.dataset.order !== "asc";
+		th.dataset.order = asc ? "asc" : "desc";
+		var numeric = th.dataset.type === "num";
+		var rows = Array.from(body.rows);
+		rows.sort(function (a, b) {
+			var x = a.cells[col].textContent, y = b.cells[col].textContent;
+			var cmp = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+			return asc ? cmp : -cmp;
+		});
+		rows.forEach(function (row) { body.appendChild(row); });
+	});
+});
+</script>
+</body>
+</html>
+`))
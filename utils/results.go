@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Result is a single response that passed the filters along with the request that produced it
+type Result struct {
+	Code       int      `json:"code"`
+	Size       int      `json:"size"`
+	Words      int      `json:"words"`
+	Lines      int      `json:"lines"`
+	Time       int      `json:"time"`
+	Positions  []string `json:"positions"`
+	Transforms []string `json:"transforms"`
+	Url        string   `json:"url"`
+	Method     string   `json:"method"`
+	Step       int      `json:"step"`
+	Headers    []string `json:"headers"`
+	Proxy      string   `json:"proxy,omitempty"`
+	Proto      string   `json:"proto"`
+}
+
+var csvHeader = []string{"code", "size", "words", "lines", "time", "positions", "transforms", "url", "method", "step", "headers", "proxy", "proto"}
+
+// ResultWriter writes results to a file as they come in. Supported formats are json, jsonl and csv
+type ResultWriter struct {
+	Channel   io.Writer
+	format    string
+	count     int
+	csvWriter *csv.Writer
+	closed    bool
+	lock      sync.Mutex
+}
+
+// ResultFormatFromName guesses the output format from the extension of the output file,
+// defaults to jsonl
+func ResultFormatFromName(fname string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(fname)), ".")
+	switch ext {
+	case "json", "csv":
+		return ext
+	}
+	return "jsonl"
+}
+
+func NewResultWriter(channel io.Writer, format string) (*ResultWriter, error) {
+	w := &ResultWriter{
+		Channel: channel,
+		format:  strings.ToLower(format),
+	}
+	var err error
+	switch w.format {
+	case "json":
+		_, err = io.WriteString(channel, "[")
+	case "jsonl":
+	case "csv":
+		w.csvWriter = csv.NewWriter(channel)
+		err = w.csvWriter.Write(csvHeader)
+	default:
+		return nil, errors.New("unknown output format " + format + ", expected one of json, jsonl or csv")
+	}
+	return w, err
+}
+
+// Write records a result, it is safe to call from multiple threads. Results that come in after the writer was closed
+// by an interrupt are dropped
+func (w *ResultWriter) Write(r Result) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	var err error
+	switch w.format {
+	case "json", "jsonl":
+		var line []byte
+		line, err = json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if w.format == "json" {
+			sep := ",\n"
+			if w.count == 0 {
+				sep = "\n"
+			}
+			line = append([]byte(sep), line...)
+		} else {
+			line = append(line, '\n')
+		}
+		_, err = w.Channel.Write(line)
+	case "csv":
+		err = w.csvWriter.Write([]string{
+			strconv.Itoa(r.Code),
+			strconv.Itoa(r.Size),
+			strconv.Itoa(r.Words),
+			strconv.Itoa(r.Lines),
+			strconv.Itoa(r.Time),
+			strings.Join(r.Positions, "\n"),
+			strings.Join(r.Transforms, "\n"),
+			r.Url,
+			r.Method,
+			strconv.Itoa(r.Step),
+			strings.Join(r.Headers, "\n"),
+			r.Proxy,
+			r.Proto,
+		})
+		if err == nil {
+			w.csvWriter.Flush()
+			err = w.csvWriter.Error()
+		}
+	}
+	w.count++
+	return err
+}
+
+// Close finishes the output and closes the channel if it can be closed
+func (w *ResultWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.format == "json" {
+		_, err = io.WriteString(w.Channel, "\n]\n")
+	}
+	if closer, ok := w.Channel.(io.Closer); ok {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
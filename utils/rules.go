@@ -0,0 +1,413 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// the number of arguments taken by each hashcat rule function
+var ruleArgs = map[byte]int{
+	':': 0, 'l': 0, 'u': 0, 'c': 0, 'C': 0, 't': 0, 'T': 1, 'r': 0, 'd': 0, 'p': 1, 'f': 0, '{': 0, '}': 0,
+	'$': 1, '^': 1, '[': 0, ']': 0, 'D': 1, 'x': 2, 'O': 2, 'i': 2, 'o': 2, '\'': 1, 's': 2, '@': 1,
+	'z': 1, 'Z': 1, 'q': 0, 'k': 0, 'K': 0, '*': 2, 'L': 1, 'R': 1, '+': 1, '-': 1, '.': 1, ',': 1,
+	'y': 1, 'Y': 1, 'E': 0, 'e': 1,
+	'<': 1, '>': 1, '_': 1, '!': 1, '/': 1, '(': 1, ')': 1, '=': 2, '%': 2,
+}
+
+// the rule functions that take a position or length as their first argument
+var rulePositionArgs = "TpDxOio'zZ*LR+-.,yY<>_=%"
+
+// the rule functions that reject words
+var ruleRejections = "<>_!/()=%"
+
+// the rule functions whose second argument is also a position
+var ruleSecondPositionArgs = "xO*"
+
+type ruleOp struct {
+	fn   byte
+	args []byte
+	// the numeric value of the arguments for the functions that take positions
+	n int
+	m int
+}
+
+// Rules is a list of hashcat style rules applied to the words of one of the wordlists
+type Rules struct {
+	rules     [][]ruleOp
+	canReject bool
+	Position  int
+}
+
+// LoadRules reads a hashcat rule file, each line is a rule that produces one candidate from a word
+func LoadRules(fname string, position int) (*Rules, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f, position)
+}
+
+// ParseRules parses hashcat rules, one per line. Empty lines and lines starting with # are skipped
+func ParseRules(r io.Reader, position int) (*Rules, error) {
+	rules := &Rules{Position: position}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNum, err.Error())
+		}
+		for _, op := range rule {
+			rules.canReject = rules.canReject || strings.IndexByte(ruleRejections, op.fn) >= 0
+		}
+		rules.rules = append(rules.rules, rule)
+	}
+	if len(rules.rules) <= 0 {
+		return nil, errors.New("no rules found")
+	}
+	return rules, scanner.Err()
+}
+
+// rulePosition converts a position argument, 0-9 followed by A-Z for 10-35
+func rulePosition(c byte) (int, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, nil
+	}
+	return 0, fmt.Errorf("invalid position %c", c)
+}
+
+func parseRule(line string) ([]ruleOp, error) {
+	rule := []ruleOp{}
+	for i := 0; i < len(line); i++ {
+		fn := line[i]
+		// spaces between functions are allowed
+		if fn == ' ' || fn == '\t' {
+			continue
+		}
+		numArgs, ok := ruleArgs[fn]
+		if !ok {
+			return nil, fmt.Errorf("unsupported rule function %c in %s", fn, line)
+		}
+		if i+numArgs >= len(line) {
+			return nil, fmt.Errorf("missing argument for %c in %s", fn, line)
+		}
+		op := ruleOp{fn: fn, args: []byte(line[i+1 : i+1+numArgs])}
+		var err error
+		if strings.IndexByte(rulePositionArgs, fn) >= 0 {
+			op.n, err = rulePosition(op.args[0])
+		}
+		if err == nil && strings.IndexByte(ruleSecondPositionArgs, fn) >= 0 {
+			op.m, err = rulePosition(op.args[1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s for %c in %s", err.Error(), fn, line)
+		}
+		rule = append(rule, op)
+		i += numArgs
+	}
+	return rule, nil
+}
+
+// Len returns the number of rules
+func (r *Rules) Len() int {
+	return len(r.rules)
+}
+
+// Apply runs every rule on the word and returns the candidates that weren't rejected
+func (r *Rules) Apply(word string) []string {
+	candidates := []string{}
+	for _, rule := range r.rules {
+		candidate, ok := applyRule(rule, []byte(word))
+		if ok {
+			candidates = append(candidates, string(candidate))
+		}
+	}
+	return candidates
+}
+
+// Count returns the number of candidates the rules produce from a wordlist
+func (r *Rules) Count(wordlistLen int, open func() (io.ReadCloser, error)) (int, error) {
+	if !r.canReject {
+		return wordlistLen * r.Len(), nil
+	}
+	// rules that reject words need to be run to know how many candidates there are
+	f, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		count += len(r.Apply(scanner.Text()))
+	}
+	return count, scanner.Err()
+}
+
+func toggleCase(c byte) byte {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 32
+	case c >= 'A' && c <= 'Z':
+		return c + 32
+	}
+	return c
+}
+
+func reverseBytes(w []byte) []byte {
+	r := make([]byte, len(w))
+	for i, c := range w {
+		r[len(w)-1-i] = c
+	}
+	return r
+}
+
+// applyRule runs each function of a rule on the word in order. Functions with positions outside of the word leave
+// the word as it is. Returns false if the word was rejected
+func applyRule(rule []ruleOp, w []byte) ([]byte, bool) {
+	for _, op := range rule {
+		n, m := op.n, op.m
+		var x, y byte
+		if len(op.args) > 0 {
+			x = op.args[len(op.args)-1]
+			y = x
+			if len(op.args) > 1 {
+				x, y = op.args[0], op.args[1]
+			}
+		}
+		switch op.fn {
+		case ':':
+		case 'l':
+			w = bytes.ToLower(w)
+		case 'u':
+			w = bytes.ToUpper(w)
+		case 'c':
+			w = bytes.ToLower(w)
+			if len(w) > 0 {
+				w[0] = toggleCase(w[0])
+			}
+		case 'C':
+			w = bytes.ToUpper(w)
+			if len(w) > 0 {
+				w[0] = toggleCase(w[0])
+			}
+		case 't':
+			for i := range w {
+				w[i] = toggleCase(w[i])
+			}
+		case 'T':
+			if n < len(w) {
+				w[n] = toggleCase(w[n])
+			}
+		case 'r':
+			w = reverseBytes(w)
+		case 'd':
+			w = append(w, w...)
+		case 'p':
+			w = bytes.Repeat(w, n+1)
+		case 'f':
+			w = append(w, reverseBytes(w)...)
+		case '{':
+			if len(w) > 0 {
+				w = append(w[1:], w[0])
+			}
+		case '}':
+			if len(w) > 0 {
+				w = append([]byte{w[len(w)-1]}, w[:len(w)-1]...)
+			}
+		case '$':
+			w = append(w, y)
+		case '^':
+			w = append([]byte{y}, w...)
+		case '[':
+			if len(w) > 0 {
+				w = w[1:]
+			}
+		case ']':
+			if len(w) > 0 {
+				w = w[:len(w)-1]
+			}
+		case 'D':
+			if n < len(w) {
+				w = append(w[:n:n], w[n+1:]...)
+			}
+		case 'x':
+			if n < len(w) && n+m <= len(w) {
+				w = append([]byte{}, w[n:n+m]...)
+			}
+		case 'O':
+			if n < len(w) && n+m <= len(w) {
+				w = append(w[:n:n], w[n+m:]...)
+			}
+		case 'i':
+			if n <= len(w) {
+				w = append(w[:n:n], append([]byte{y}, w[n:]...)...)
+			}
+		case 'o':
+			if n < len(w) {
+				w[n] = y
+			}
+		case '\'':
+			if n < len(w) {
+				w = w[:n]
+			}
+		case 's':
+			w = bytes.ReplaceAll(w, []byte{x}, []byte{y})
+		case '@':
+			w = bytes.ReplaceAll(w, []byte{y}, []byte{})
+		case 'z':
+			if len(w) > 0 {
+				w = append(bytes.Repeat(w[:1], n), w...)
+			}
+		case 'Z':
+			if len(w) > 0 {
+				w = append(w, bytes.Repeat(w[len(w)-1:], n)...)
+			}
+		case 'q':
+			doubled := []byte{}
+			for _, c := range w {
+				doubled = append(doubled, c, c)
+			}
+			w = doubled
+		case 'k':
+			if len(w) > 1 {
+				w[0], w[1] = w[1], w[0]
+			}
+		case 'K':
+			if len(w) > 1 {
+				w[len(w)-1], w[len(w)-2] = w[len(w)-2], w[len(w)-1]
+			}
+		case '*':
+			if n < len(w) && m < len(w) {
+				w[n], w[m] = w[m], w[n]
+			}
+		case 'L':
+			if n < len(w) {
+				w[n] <<= 1
+			}
+		case 'R':
+			if n < len(w) {
+				w[n] >>= 1
+			}
+		case '+':
+			if n < len(w) {
+				w[n]++
+			}
+		case '-':
+			if n < len(w) {
+				w[n]--
+			}
+		case '.':
+			if n+1 < len(w) {
+				w[n] = w[n+1]
+			}
+		case ',':
+			if n > 0 && n < len(w) {
+				w[n] = w[n-1]
+			}
+		case 'y':
+			if n <= len(w) {
+				w = append(append([]byte{}, w[:n]...), w...)
+			}
+		case 'Y':
+			if n <= len(w) {
+				w = append(w, w[len(w)-n:]...)
+			}
+		case 'E', 'e':
+			sep := byte(' ')
+			if op.fn == 'e' {
+				sep = y
+			}
+			w = bytes.ToLower(w)
+			for i := range w {
+				if i == 0 || w[i-1] == sep {
+					w[i] = toggleCase(w[i])
+				}
+			}
+		case '<':
+			if len(w) > n {
+				return nil, false
+			}
+		case '>':
+			if len(w) < n {
+				return nil, false
+			}
+		case '_':
+			if len(w) != n {
+				return nil, false
+			}
+		case '!':
+			if bytes.IndexByte(w, y) >= 0 {
+				return nil, false
+			}
+		case '/':
+			if bytes.IndexByte(w, y) < 0 {
+				return nil, false
+			}
+		case '(':
+			if len(w) == 0 || w[0] != y {
+				return nil, false
+			}
+		case ')':
+			if len(w) == 0 || w[len(w)-1] != y {
+				return nil, false
+			}
+		case '=':
+			if n >= len(w) || w[n] != y {
+				return nil, false
+			}
+		case '%':
+			if bytes.Count(w, []byte{y}) < n {
+				return nil, false
+			}
+		}
+	}
+	return w, true
+}
+
+// rulesReader expands every line of a wordlist into the candidates produced by the rules
+type rulesReader struct {
+	source  io.ReadCloser
+	scanner *bufio.Scanner
+	rules   *Rules
+	buf     []byte
+}
+
+// NewRulesReader applies the rules to every word of the wordlist as it is read
+func NewRulesReader(source io.ReadCloser, rules *Rules) io.ReadCloser {
+	return &rulesReader{source: source, scanner: bufio.NewScanner(source), rules: rules}
+}
+
+func (r *rulesReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) && r.scanner.Scan() {
+		for _, candidate := range r.rules.Apply(r.scanner.Text()) {
+			r.buf = append(r.buf, candidate...)
+			r.buf = append(r.buf, '\n')
+		}
+	}
+	if len(r.buf) <= 0 {
+		if err := r.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *rulesReader) Close() error {
+	return r.source.Close()
+}
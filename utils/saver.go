@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RequestSaver writes the request, response and curl command of every hit to a directory so they can be replayed
+type RequestSaver struct {
+	dir string
+}
+
+// NewRequestSaver creates the directory if it doesn't exist yet
+func NewRequestSaver(dir string) (*RequestSaver, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &RequestSaver{dir: dir}, nil
+}
+
+// Name gets the file name for a hit without the extension. It's a hash of the positions so the same words always
+// give the same name, steps after the first in a request chain get their own files
+func (s *RequestSaver) Name(positions []string, step int) string {
+	hash := sha256.Sum256([]byte(strings.Join(positions, "\x00")))
+	name := hex.EncodeToString(hash[:8])
+	if step > 0 {
+		name += "-step" + strconv.Itoa(step)
+	}
+	return name
+}
+
+// Save writes the request as a request file (.req), the response (.resp) and the curl command (.curl)
+func (s *RequestSaver) Save(positions []string, step int, request string, response string, curl string) error {
+	base := filepath.Join(s.dir, s.Name(positions, step))
+	files := map[string]string{".req": request, ".resp": response, ".curl": curl + "\n"}
+	for ext, content := range files {
+		err := os.WriteFile(base+ext, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"regexp"
@@ -99,24 +100,35 @@ func ReplacePosition(str string, positions []string, recursePos int, log *Logger
 }
 
 // PrintProgressLoop prints the current progress to stdout every second and adds the current request/second to an array
-func PrintProgressLoop(counter *Counter, dos bool, log *Logger) {
+func PrintProgressLoop(counter *Counter, dos bool, limiter *RateLimiter, log *Logger) {
 	for {
 		time.Sleep(1 * time.Second)
 		counter.UpdateAvg()
-		PrintProgress(counter, dos, log)
+		PrintProgress(counter, dos, limiter, log)
 	}
 }
 
 // PrintProgress prints the formatted progress string to stdout and computes the request/second average using an array
-// populated by PrintProgressLoop
-func PrintProgress(counter *Counter, dos bool, log *Logger) {
+// populated by PrintProgressLoop. The current rate is shown when the rate limit is adaptive
+func PrintProgress(counter *Counter, dos bool, limiter *RateLimiter, log *Logger) {
 	avg := counter.GetCountAvg()
 	var progressString string
-	if !dos {
+	if !dos && TotalJobs < 0 {
+		progressString = fmt.Sprintf("\r\033[KProgress: %d/? - %d/s - Errors: %d", counter.GetCountNum(), avg, counter.GetErrorNum())
+	} else if !dos {
 		progressString = fmt.Sprintf("\r\033[KProgress: %d/%d - %d/s - Errors: %d", counter.GetCountNum(), TotalJobs, avg, counter.GetErrorNum())
 	} else {
 		progressString = fmt.Sprintf("\r\033[KProgress: %d - %d/s - Errors: %d", counter.GetCountNum(), avg, counter.GetErrorNum())
 	}
+	if limiter != nil {
+		if paused := limiter.PausedFor(); paused > 0 {
+			progressString += fmt.Sprintf(" - Limit: paused %ds", int(paused.Seconds()+0.5))
+		} else if rate := limiter.Rate(); rate > 0 {
+			progressString += fmt.Sprintf(" - Limit: %.1f/s", rate)
+		} else {
+			progressString += " - Limit: none"
+		}
+	}
 	log.Print(progressString)
 }
 
@@ -149,22 +161,47 @@ func SetDif(a, b []int) (diff []int) {
 }
 
 // GetNumJobs computes the number of jobs based on the file length and number of fuzzing positions
-// Returns the total number of jobs
-func GetNumJobs(fnames []string, combo bool, extensions []string, log *Logger) int {
-	var files []*bufio.Scanner
+// and the candidates the rules produce for their wordlist if rules is set.
+// Returns the total number of jobs, or -1 if it isn't known ahead of time
+func GetNumJobs(fnames []string, combo bool, extensions []string, rules *Rules, log *Logger) int {
 	for _, fname := range fnames {
-		f, err := os.Open(fname)
-		if err != nil {
-			log.Printf("Error opening %s\n", fnames[0])
-			os.Exit(1)
+		// streams can't be counted without reading them
+		if IsStreamWordlist(fname) {
+			return -1
+		}
+	}
+	var lengths []int
+	for i, fname := range fnames {
+		length := 0
+		if IsGeneratorWordlist(fname) {
+			count, err := GeneratorLen(fname)
+			if err != nil {
+				log.Printf("Error: %s in %s\n", err.Error(), fname)
+				os.Exit(1)
+			}
+			length = count
+		} else {
+			f, err := os.Open(fname)
+			if err != nil {
+				log.Printf("Error opening %s\n", fname)
+				os.Exit(1)
+			}
+			length = getFileLen(bufio.NewScanner(f))
+			f.Close()
+		}
+		if rules != nil && rules.Position == i {
+			count, err := rules.Count(length, func() (io.ReadCloser, error) { return OpenWordlist(fname, false) })
+			if err != nil {
+				log.Printf("Error applying rules to %s: %s\n", fname, err.Error())
+				os.Exit(1)
+			}
+			length = count
 		}
-		files = append(files, bufio.NewScanner(f))
-		defer f.Close()
+		lengths = append(lengths, length)
 	}
 	// there will always be at least one file
-	numJobs := getFileLen(files[0])
-	for _, f := range files[1:] {
-		len := getFileLen(f)
+	numJobs := lengths[0]
+	for _, len := range lengths[1:] {
 		if len == 0 {
 			log.Println("Error: empty file")
 			os.Exit(1)
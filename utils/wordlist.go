@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// streamWordlist is a wordlist read from stdin or a command. Streams can only be read once, so the content is kept
+// in memory when the wordlist needs to be read again
+type streamWordlist struct {
+	content []byte
+	done    bool
+}
+
+var streamWordlists = map[string]*streamWordlist{}
+var streamLock sync.Mutex
+
+// IsStreamWordlist returns true if the wordlist is read from stdin (-) or the output of a command (cmd:<command>)
+// instead of a file
+func IsStreamWordlist(name string) bool {
+	return name == "-" || strings.HasPrefix(name, "cmd:")
+}
+
+// OpenWordlist opens a wordlist file, stdin (-), the output of a command (cmd:<command>) or a generator.
+// When keep is true the content of a stream is kept so it can be opened again once it has been read completely
+func OpenWordlist(name string, keep bool) (io.ReadCloser, error) {
+	if IsGeneratorWordlist(name) {
+		gen, err := newGenerator(name)
+		if err != nil {
+			return nil, err
+		}
+		return &generatorReader{gen: gen}, nil
+	}
+	if !IsStreamWordlist(name) {
+		return os.Open(name)
+	}
+	streamLock.Lock()
+	defer streamLock.Unlock()
+	stream, ok := streamWordlists[name]
+	if ok {
+		if !stream.done {
+			return nil, errors.New("wordlist " + name + " can only be read once")
+		}
+		return io.NopCloser(bytes.NewReader(stream.content)), nil
+	}
+	stream = &streamWordlist{}
+	streamWordlists[name] = stream
+
+	var source io.ReadCloser
+	var cmd *exec.Cmd
+	if name == "-" {
+		source = io.NopCloser(os.Stdin)
+	} else {
+		command := strings.TrimPrefix(name, "cmd:")
+		cmd = exec.Command("sh", "-c", command)
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/c", command)
+		}
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		err = cmd.Start()
+		if err != nil {
+			return nil, err
+		}
+		source = stdout
+	}
+	return &streamReader{stream: stream, source: source, cmd: cmd, keep: keep}, nil
+}
+
+// streamReader reads a stream wordlist and keeps a copy of it if needed
+type streamReader struct {
+	stream *streamWordlist
+	source io.ReadCloser
+	cmd    *exec.Cmd
+	keep   bool
+	eof    bool
+	buf    bytes.Buffer
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	n, err := s.source.Read(p)
+	if s.keep {
+		s.buf.Write(p[:n])
+	}
+	if err == io.EOF {
+		s.eof = true
+		streamLock.Lock()
+		// a stream that isn't kept can't be read again
+		s.stream.done = s.keep
+		s.stream.content = s.buf.Bytes()
+		streamLock.Unlock()
+	}
+	return n, err
+}
+
+// Close closes the stream. A stream that's kept is read to the end first, since combo mode stops at the shortest
+// wordlist and the rest is still needed when it's opened again
+func (s *streamReader) Close() error {
+	if s.keep && !s.eof {
+		io.Copy(io.Discard, s)
+	}
+	err := s.source.Close()
+	if s.cmd != nil {
+		s.cmd.Wait()
+	}
+	return err
+}